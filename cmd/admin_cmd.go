@@ -1,16 +1,23 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
 	"github.com/gofrs/uuid"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/crypto"
 	"github.com/supabase/auth/internal/models"
 	"github.com/supabase/auth/internal/storage"
 )
 
 var autoconfirm, isAdmin bool
 var audience string
+var page, perPage uint64
 
 func getAudience(c *conf.GlobalConfiguration) string {
 	if audience == "" {
@@ -25,17 +32,71 @@ func adminCmd() *cobra.Command {
 		Use: "admin",
 	}
 
-	adminCmd.AddCommand(&adminCreateUserCmd, &adminDeleteUserCmd)
+	var userCmd = &cobra.Command{
+		Use:   "user",
+		Short: "Manage users",
+	}
+	userCmd.AddCommand(&adminUserCreateCmd, &adminUserDeleteCmd, &adminUserListCmd)
+
+	var secretCmd = &cobra.Command{
+		Use:   "secret",
+		Short: "Manage the JWT secret",
+	}
+	secretCmd.AddCommand(&adminSecretRotateCmd)
+
+	adminCmd.AddCommand(userCmd, secretCmd, &adminCreateUserCmd, &adminDeleteUserCmd)
 	adminCmd.PersistentFlags().StringVarP(&audience, "aud", "a", "", "Set the new user's audience")
 
-	adminCreateUserCmd.Flags().BoolVar(&autoconfirm, "confirm", false, "Automatically confirm user without sending an email")
-	adminCreateUserCmd.Flags().BoolVar(&isAdmin, "admin", false, "Create user with admin privileges")
+	for _, cmd := range []*cobra.Command{&adminCreateUserCmd, &adminUserCreateCmd} {
+		cmd.Flags().BoolVar(&autoconfirm, "confirm", false, "Automatically confirm user without sending an email")
+		cmd.Flags().BoolVar(&isAdmin, "admin", false, "Create user with admin privileges")
+	}
+
+	adminUserListCmd.Flags().Uint64Var(&page, "page", 1, "Page of users to list")
+	adminUserListCmd.Flags().Uint64Var(&perPage, "per-page", 50, "Number of users per page")
 
 	return adminCmd
 }
 
+var adminUserCreateCmd = cobra.Command{
+	Use:   "create <email> <password> [role]",
+	Short: "Create a user",
+	Args:  cobra.RangeArgs(2, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		execWithConfigAndArgs(cmd, adminCreateUser, args)
+	},
+}
+
+var adminUserDeleteCmd = cobra.Command{
+	Use:   "delete <id or email>",
+	Short: "Delete a user",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		execWithConfigAndArgs(cmd, adminDeleteUser, args)
+	},
+}
+
+var adminUserListCmd = cobra.Command{
+	Use:   "list",
+	Short: "List the users of the audience",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		execWithConfigAndArgs(cmd, adminListUsers, args)
+	},
+}
+
+var adminSecretRotateCmd = cobra.Command{
+	Use:   "rotate",
+	Short: "Generate a new JWT secret and keep accepting the current one",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		execWithConfigAndArgs(cmd, adminRotateSecret, args)
+	},
+}
+
 var adminCreateUserCmd = cobra.Command{
-	Use: "createuser",
+	Use:        "createuser",
+	Deprecated: "use admin user create instead",
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) < 2 {
 			logrus.Fatal("Not enough arguments to createuser command. Expected at least email and password values")
@@ -47,7 +108,8 @@ var adminCreateUserCmd = cobra.Command{
 }
 
 var adminDeleteUserCmd = cobra.Command{
-	Use: "deleteuser",
+	Use:        "deleteuser",
+	Deprecated: "use admin user delete instead",
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) < 1 {
 			logrus.Fatal("Not enough arguments to deleteuser command. Expected at least ID or email")
@@ -129,3 +191,48 @@ func adminDeleteUser(config *conf.GlobalConfiguration, args []string) {
 
 	logrus.Infof("Removed user: %s", args[0])
 }
+
+func adminListUsers(config *conf.GlobalConfiguration, args []string) {
+	db, err := storage.Dial(config)
+	if err != nil {
+		logrus.Fatalf("Error opening database: %+v", err)
+	}
+	defer db.Close()
+
+	pageParams := &models.Pagination{Page: page, PerPage: perPage}
+	sortParams := &models.SortParams{Fields: []models.SortField{{Name: "created_at", Dir: models.Descending}}}
+	users, err := models.FindUsersInAudience(db, getAudience(config), pageParams, sortParams, "")
+	if err != nil {
+		logrus.Fatalf("Error finding users: %+v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tEMAIL\tPHONE\tROLE\tCREATED AT\tLAST SIGN IN AT")
+	for _, user := range users {
+		lastSignInAt := ""
+		if user.LastSignInAt != nil {
+			lastSignInAt = user.LastSignInAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", user.ID, user.GetEmail(), user.GetPhone(), user.Role, user.CreatedAt.Format(time.RFC3339), lastSignInAt)
+	}
+	if err := w.Flush(); err != nil {
+		logrus.Fatalf("Error listing users: %+v", err)
+	}
+
+	logrus.Infof("Listed %d of %d users", len(users), pageParams.Count)
+}
+
+// adminRotateSecret prints a new JWT secret. Setting it as JWT_SECRET and
+// the current secret as JWT_PREVIOUS_SECRET keeps the access tokens that were
+// signed with the current secret valid until they expire. The current secret
+// isn't printed, so that it doesn't end up in terminal logs.
+func adminRotateSecret(config *conf.GlobalConfiguration, args []string) {
+	if config.JWT.PreviousSecret != "" {
+		logrus.Warn("JWT_PREVIOUS_SECRET is set, access tokens signed with it won't be accepted anymore")
+	}
+
+	fmt.Printf("GOTRUE_JWT_SECRET=%s\n", crypto.SecureToken(32))
+	fmt.Printf("GOTRUE_JWT_PREVIOUS_SECRET_EXPIRES_AT=%s\n", time.Now().Add(time.Duration(config.JWT.Exp)*time.Second).UTC().Format(time.RFC3339))
+
+	logrus.Info("Move the current GOTRUE_JWT_SECRET to GOTRUE_JWT_PREVIOUS_SECRET, set these values and restart or reload Auth")
+}
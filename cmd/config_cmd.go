@@ -0,0 +1,238 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gobwas/glob"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/supabase/auth/internal/conf"
+	"gopkg.in/gomail.v2"
+)
+
+var checkSMTP, checkTemplates bool
+
+func configCmd() *cobra.Command {
+	var configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration",
+	}
+
+	configCmd.AddCommand(&configValidateCmd)
+	configValidateCmd.Flags().BoolVar(&checkSMTP, "smtp", false, "Check that the SMTP server accepts the configured credentials")
+	configValidateCmd.Flags().BoolVar(&checkTemplates, "templates", false, "Fetch the email templates from their URLs and check their syntax")
+
+	return configCmd
+}
+
+var configValidateCmd = cobra.Command{
+	Use:   "validate",
+	Short: "Validate the configuration and report all errors",
+	Args:  cobra.NoArgs,
+	Run:   validateConfig,
+}
+
+// configError is a single problem found by config validate.
+type configError struct {
+	Field string `json:"field"`
+	Error string `json:"error"`
+}
+
+// configReport is printed as JSON by config validate.
+type configReport struct {
+	Valid  bool          `json:"valid"`
+	Errors []configError `json:"errors"`
+}
+
+func (r *configReport) add(field string, err error) {
+	r.Errors = append(r.Errors, configError{Field: field, Error: err.Error()})
+}
+
+func validateConfig(cmd *cobra.Command, args []string) {
+	// loading the configuration stops at the first error, the other checks
+	// need a loaded configuration
+	config, err := conf.LoadGlobal(configFile)
+	var report *configReport
+	if err != nil {
+		report = &configReport{Errors: []configError{}}
+		report.add("config", err)
+	} else {
+		report = newConfigReport(config)
+	}
+
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		logrus.Fatalf("Error encoding report: %+v", err)
+	}
+	fmt.Println(string(out))
+
+	if !report.Valid {
+		os.Exit(1)
+	}
+}
+
+// newConfigReport checks the loaded configuration, the network checks only
+// run if their flags are set.
+func newConfigReport(config *conf.GlobalConfiguration) *configReport {
+	report := &configReport{Errors: []configError{}}
+
+	validateExternalProviders(report, config)
+	validateURLs(report, config)
+	validateEmailSubjects(report, config)
+
+	if checkTemplates {
+		validateEmailTemplates(report, config)
+	}
+	if checkSMTP {
+		validateSMTP(report, config)
+	}
+
+	report.Valid = len(report.Errors) == 0
+	return report
+}
+
+func validateExternalProviders(report *configReport, config *conf.GlobalConfiguration) {
+	providers := reflect.ValueOf(config.External)
+	for i := 0; i < providers.NumField(); i++ {
+		provider, ok := providers.Field(i).Interface().(conf.OAuthProviderConfiguration)
+		if !ok || !provider.Enabled {
+			continue
+		}
+
+		name := "external." + strings.Split(providers.Type().Field(i).Tag.Get("json"), ",")[0]
+		if err := provider.ValidateOAuth(); err != nil {
+			report.add(name, err)
+			continue
+		}
+
+		for _, clientID := range provider.ClientID {
+			if clientID == "" || strings.TrimSpace(clientID) != clientID {
+				report.add(name+".client_id", fmt.Errorf("client ID %q is empty or has surrounding whitespace", clientID))
+			}
+		}
+		if strings.TrimSpace(provider.Secret) != provider.Secret {
+			report.add(name+".secret", errors.New("secret has surrounding whitespace"))
+		}
+
+		if err := validateAbsoluteURL(provider.RedirectURI); err != nil {
+			report.add(name+".redirect_uri", err)
+		}
+		if provider.URL != "" {
+			if err := validateAbsoluteURL(provider.URL); err != nil {
+				report.add(name+".url", err)
+			}
+		}
+		if provider.ApiURL != "" {
+			if err := validateAbsoluteURL(provider.ApiURL); err != nil {
+				report.add(name+".api_url", err)
+			}
+		}
+	}
+}
+
+func validateURLs(report *configReport, config *conf.GlobalConfiguration) {
+	if err := validateAbsoluteURL(config.SiteURL); err != nil {
+		report.add("site_url", err)
+	}
+
+	// the entries are globs, compiled the same way as in conf.ApplyDefaults
+	for _, uri := range config.URIAllowList {
+		if _, err := glob.Compile(uri, '.', '/'); err != nil {
+			report.add("uri_allow_list", fmt.Errorf("invalid entry %q: %w", uri, err))
+		}
+	}
+}
+
+func validateAbsoluteURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("%q is not an absolute URL", rawURL)
+	}
+	return nil
+}
+
+// validateEmailSubjects parses the subjects the same way the mailer does when
+// sending an email.
+func validateEmailSubjects(report *configReport, config *conf.GlobalConfiguration) {
+	subjects := reflect.ValueOf(config.Mailer.Subjects)
+	for i := 0; i < subjects.NumField(); i++ {
+		name := strings.Split(subjects.Type().Field(i).Tag.Get("json"), ",")[0]
+
+		if subject := subjects.Field(i).String(); subject != "" {
+			if _, err := template.New(name).Parse(subject); err != nil {
+				report.add("mailer.subjects."+name, err)
+			}
+		}
+	}
+}
+
+// validateEmailTemplates fetches and parses the templates the same way the
+// mailer does when sending an email.
+func validateEmailTemplates(report *configReport, config *conf.GlobalConfiguration) {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	templates := reflect.ValueOf(config.Mailer.Templates)
+	for i := 0; i < templates.NumField(); i++ {
+		name := strings.Split(templates.Type().Field(i).Tag.Get("json"), ",")[0]
+
+		if templateURL := templates.Field(i).String(); templateURL != "" {
+			if !strings.HasPrefix(templateURL, "http") {
+				templateURL = config.SiteURL + templateURL
+			}
+			if err := validateEmailTemplate(client, name, templateURL); err != nil {
+				report.add("mailer.templates."+name, err)
+			}
+		}
+	}
+}
+
+func validateEmailTemplate(client *http.Client, name, templateURL string) error {
+	resp, err := client.Get(templateURL) // #nosec G107
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching %s returned status %d", templateURL, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	_, err = htmltemplate.New(name).Parse(string(body))
+	return err
+}
+
+func validateSMTP(report *configReport, config *conf.GlobalConfiguration) {
+	if config.SMTP.Host == "" {
+		report.add("smtp.host", errors.New("no SMTP host is configured"))
+		return
+	}
+
+	dialer := gomail.NewDialer(config.SMTP.Host, config.SMTP.Port, config.SMTP.User, config.SMTP.Pass)
+	closer, err := dialer.Dial()
+	if err != nil {
+		report.add("smtp", err)
+		return
+	}
+	if err := closer.Close(); err != nil {
+		report.add("smtp", err)
+	}
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/conf"
+)
+
+func validTestConfig() *conf.GlobalConfiguration {
+	config := &conf.GlobalConfiguration{}
+	config.SiteURL = "http://localhost:3000"
+	config.URIAllowList = []string{"http://localhost:3000/**"}
+	return config
+}
+
+func reportedFields(report *configReport) []string {
+	fields := []string{}
+	for _, err := range report.Errors {
+		fields = append(fields, err.Field)
+	}
+	return fields
+}
+
+func TestValidateExternalProviders(t *testing.T) {
+	config := validTestConfig()
+	config.External.Github = conf.OAuthProviderConfiguration{
+		Enabled:     true,
+		ClientID:    []string{" client-id"},
+		Secret:      "secret",
+		RedirectURI: "/callback",
+	}
+	config.External.Google = conf.OAuthProviderConfiguration{
+		Enabled:     true,
+		ClientID:    []string{"client-id"},
+		RedirectURI: "http://localhost:9999/callback",
+	}
+	// disabled providers aren't checked
+	config.External.Gitlab = conf.OAuthProviderConfiguration{
+		RedirectURI: "/callback",
+	}
+
+	report := &configReport{}
+	validateExternalProviders(report, config)
+	assert.ElementsMatch(t, []string{
+		"external.github.client_id",
+		"external.github.redirect_uri",
+		"external.google",
+	}, reportedFields(report))
+}
+
+func TestValidateURLs(t *testing.T) {
+	config := validTestConfig()
+	config.SiteURL = "/relative"
+	// entries without a scheme are valid globs
+	config.URIAllowList = []string{"http://localhost:3000/**", "localhost:3000", "**.example.com/**", "http://[localhost"}
+
+	report := &configReport{}
+	validateURLs(report, config)
+	assert.ElementsMatch(t, []string{"site_url", "uri_allow_list"}, reportedFields(report))
+	assert.Contains(t, report.Errors[1].Error, `"http://[localhost"`)
+}
+
+func TestNewConfigReport(t *testing.T) {
+	report := newConfigReport(validTestConfig())
+	assert.True(t, report.Valid)
+	assert.Empty(t, report.Errors)
+
+	config := validTestConfig()
+	config.Mailer.Subjects.Invite = "Join {{ .SiteURL"
+	report = newConfigReport(config)
+	assert.False(t, report.Valid)
+	assert.Equal(t, []string{"mailer.subjects.invite"}, reportedFields(report))
+}
+
+func TestNewConfigReportTemplates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<p>{{ .ConfirmationURL </p>"))
+	}))
+	defer server.Close()
+
+	config := validTestConfig()
+	config.Mailer.Templates.Invite = server.URL + "/invite.html"
+
+	// the templates are only fetched with --templates
+	report := newConfigReport(config)
+	require.True(t, report.Valid)
+
+	checkTemplates = true
+	defer func() {
+		checkTemplates = false
+	}()
+	report = newConfigReport(config)
+	assert.False(t, report.Valid)
+	assert.Equal(t, []string{"mailer.templates.invite"}, reportedFields(report))
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/supabase/auth/internal/storage"
 )
 
 var migrateCmd = cobra.Command{
@@ -57,55 +59,23 @@ func migrate(cmd *cobra.Command, args []string) {
 	} else {
 		processedUrl = fmt.Sprintf("%s?application_name=gotrue_migrations", processedUrl)
 	}
-	deets := &pop.ConnectionDetails{
-		Dialect: globalConfig.DB.Driver,
-		URL:     processedUrl,
-	}
-	deets.Options = map[string]string{
-		"migration_table_name": "schema_migrations",
-		"Namespace":            globalConfig.DB.Namespace,
-	}
-
-	db, err := pop.NewConnection(deets)
-	if err != nil {
-		log.Fatalf("%+v", errors.Wrap(err, "opening db connection"))
-	}
-	defer db.Close()
+	globalConfig.DB.URL = processedUrl
 
-	if err := db.Open(); err != nil {
-		log.Fatalf("%+v", errors.Wrap(err, "checking database connection"))
-	}
-
-	log.Debugf("Reading migrations from %s", globalConfig.DB.MigrationsPath)
-	mig, err := pop.NewFileMigrator(globalConfig.DB.MigrationsPath, db)
-	if err != nil {
-		log.Fatalf("%+v", errors.Wrap(err, "creating db migrator"))
+	if globalConfig.DB.MigrationsPath != "" {
+		log.Debugf("Reading migrations from %s", globalConfig.DB.MigrationsPath)
+	} else {
+		log.Debugf("Reading the migrations compiled into the binary")
 	}
-	log.Debugf("before status")
 
+	// the status is printed before and after the migrations are applied
+	var status io.Writer
 	if log.Level == logrus.DebugLevel {
-		err = mig.Status(os.Stdout)
-		if err != nil {
-			log.Fatalf("%+v", errors.Wrap(err, "migration status"))
-		}
+		status = os.Stdout
 	}
 
-	// turn off schema dump
-	mig.SchemaPath = ""
-
-	err = mig.Up()
-	if err != nil {
-		log.Fatalf("%v", errors.Wrap(err, "running db migrations"))
-	} else {
-		log.Infof("GoTrue migrations applied successfully")
+	if err := storage.MigrateWithStatus(globalConfig, status); err != nil {
+		log.Fatalf("%+v", err)
 	}
 
-	log.Debugf("after status")
-
-	if log.Level == logrus.DebugLevel {
-		err = mig.Status(os.Stdout)
-		if err != nil {
-			log.Fatalf("%+v", errors.Wrap(err, "migration status"))
-		}
-	}
+	log.Infof("GoTrue migrations applied successfully")
 }
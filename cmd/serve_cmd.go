@@ -3,6 +3,9 @@ package cmd
 import (
 	"context"
 	"net"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -32,7 +35,42 @@ func serve(ctx context.Context) {
 	}
 	defer db.Close()
 
-	api := api.NewAPIWithVersion(ctx, config, db, utilities.Version)
+	var opts []api.Option
+	if config.DB.ReplicaURL != "" {
+		replica, err := storage.DialReplica(config)
+		if err != nil {
+			logrus.Fatalf("error opening database replica: %+v", err)
+		}
+		defer replica.Close()
+
+		opts = append(opts, api.WithReadReplica(replica))
+	}
+
+	// requests are served with a context that isn't canceled by the
+	// shutdown signal, so that they can finish while the server drains
+	requestCtx, cancelRequests := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelRequests()
+
+	api := api.NewAPIWithVersion(requestCtx, config, db, utilities.Version, opts...)
+	api.SetConfigFile(configFile)
+
+	reloadSignal := make(chan os.Signal, 1)
+	signal.Notify(reloadSignal, syscall.SIGHUP)
+	defer signal.Stop(reloadSignal)
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case <-reloadSignal:
+				if err := api.ReloadConfig(); err != nil {
+					logrus.WithError(err).Error("unable to reload config, keeping the current config")
+				}
+			}
+		}
+	}()
 
 	addr := net.JoinHostPort(config.API.Host, config.API.Port)
 	logrus.Infof("GoTrue API started on: %s", addr)
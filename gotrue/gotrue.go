@@ -0,0 +1,165 @@
+// Package gotrue runs the API in-process, for Go programs that serve it
+// together with their own handlers instead of deploying the gotrue binary:
+//
+//	config, err := gotrue.LoadConfig("")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	db, err := gotrue.Dial(config)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	if err := gotrue.Migrate(config); err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	mux := http.NewServeMux()
+//	mux.Handle("/auth/", http.StripPrefix("/auth", gotrue.New(config, db).Handler()))
+package gotrue
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/supabase/auth/internal/api"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/mailer"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+	"github.com/supabase/auth/internal/utilities"
+)
+
+// Config is the configuration of the API, see the README for the options.
+type Config = conf.GlobalConfiguration
+
+// DB is a connection to the database. A connection opened by the program
+// itself can be used as &gotrue.DB{Connection: conn}.
+type DB = storage.Connection
+
+// MailClient sends the emails of the API, e.g. through a provider's HTTP API
+// instead of SMTP.
+type MailClient = mailer.MailClient
+
+// User is a user of the API, as returned to a GrantType.
+type User = models.User
+
+// GrantType is implemented by custom grant types of the /token endpoint,
+// see RegisterGrantType.
+type GrantType = api.GrantType
+
+// OAuthError is an OAuth 2.0 error response, e.g. returned by a GrantType
+// that can't authenticate the request.
+type OAuthError = api.OAuthError
+
+// RegisterGrantType makes a custom grant type available as the grant_type of
+// the /token endpoint, e.g. to exchange LDAP credentials. The grant type only
+// authenticates the user, the session and the tokens are issued the same way
+// as for the built-in grant types. It's meant to be called from an init
+// function and panics if the grant type is built in or already registered.
+func RegisterGrantType(name string, grantType GrantType) {
+	api.RegisterGrantType(name, grantType)
+}
+
+// NewOAuthError returns the OAuth 2.0 error with the error code, e.g.
+// invalid_grant, and description.
+func NewOAuthError(code, description string) *OAuthError {
+	return &OAuthError{Err: code, Description: description}
+}
+
+// FindUserByEmailAndAudience finds the user with the email address in the
+// audience, e.g. the JWT_AUD of the configuration.
+func FindUserByEmailAndAudience(tx *DB, email, aud string) (*User, error) {
+	return models.FindUserByEmailAndAudience(tx, email, aud)
+}
+
+// IsNotFoundError reports whether err is returned because the user or other
+// record looked up doesn't exist.
+func IsNotFoundError(err error) bool {
+	return models.IsNotFoundError(err)
+}
+
+// Option customizes the API created by New.
+type Option = api.Option
+
+// WithMiddleware runs middleware before every request is routed, after the
+// request ID is set and panics are recovered from.
+func WithMiddleware(middleware func(http.Handler) http.Handler) Option {
+	return api.WithMiddleware(middleware)
+}
+
+// WithMailClient sends the emails with client instead of the configured
+// SMTP server.
+func WithMailClient(client MailClient) Option {
+	return api.WithMailClient(client)
+}
+
+// HookEvent is the event a StorageHook is called for.
+type HookEvent = api.HookEvent
+
+// The events of a StorageHook.
+const (
+	ValidateEvent    HookEvent = api.ValidateEvent
+	SignupEvent      HookEvent = api.SignupEvent
+	EmailChangeEvent HookEvent = api.EmailChangeEvent
+	LoginEvent       HookEvent = api.LoginEvent
+)
+
+// StorageHook is called in the transaction of a hook event, such as a signup,
+// with the user as stored so far, e.g. to create a row for the user in a
+// table of the program. An error rolls back the transaction and fails the
+// request.
+type StorageHook = api.StorageHook
+
+// WithStorageHook calls hook in the transaction of every hook event.
+func WithStorageHook(hook StorageHook) Option {
+	return api.WithStorageHook(hook)
+}
+
+// WithReadReplica reads from replica where replication lag is tolerable, see
+// DB_REPLICA_URL.
+func WithReadReplica(replica *DB) Option {
+	return api.WithReadReplica(replica)
+}
+
+// LoadConfig loads the configuration from the environment, and from filename
+// if it isn't empty, the same way the gotrue binary does.
+func LoadConfig(filename string) (*Config, error) {
+	return conf.LoadGlobal(filename)
+}
+
+// Dial connects to the database of the configuration.
+func Dial(config *Config) (*DB, error) {
+	return storage.Dial(config)
+}
+
+// Migrate applies the migrations that haven't been applied yet. They're
+// compiled into the program, unless DB_MIGRATIONS_PATH is set.
+func Migrate(config *Config) error {
+	return storage.Migrate(config)
+}
+
+// API is the API running in-process.
+type API struct {
+	api *api.API
+}
+
+// New creates the API. Its configuration can be reloaded with ReloadConfig,
+// the database connection is kept.
+func New(config *Config, db *DB, opts ...Option) *API {
+	return &API{
+		api: api.NewAPIWithVersion(context.Background(), config, db, utilities.Version, opts...),
+	}
+}
+
+// Handler returns the handler serving the API.
+func (a *API) Handler() http.Handler {
+	return a.api.Handler()
+}
+
+// ReloadConfig loads the configuration again from the environment and from
+// filename if it isn't empty. If the configuration is invalid the current one
+// is kept.
+func (a *API) ReloadConfig(filename string) error {
+	a.api.SetConfigFile(filename)
+	return a.api.ReloadConfig()
+}
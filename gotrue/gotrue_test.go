@@ -0,0 +1,32 @@
+package gotrue
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWithMiddleware(t *testing.T) {
+	config, err := LoadConfig("../hack/test.env")
+	require.NoError(t, err)
+
+	var called bool
+	a := New(config, nil, WithMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			w.Header().Set("X-Embedded", "true")
+			next.ServeHTTP(w, r)
+		})
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	w := httptest.NewRecorder()
+	a.Handler().ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, called)
+	assert.Equal(t, "true", w.Header().Get("X-Embedded"))
+}
@@ -0,0 +1,52 @@
+package gotruetest
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+)
+
+// createSchema creates an empty schema in the database and returns a URL
+// connecting to it. The schema is dropped when the test finishes.
+func createSchema(t testing.TB, databaseURL string) (string, string) {
+	t.Helper()
+
+	u, err := url.Parse(databaseURL)
+	if err != nil {
+		t.Fatalf("gotruetest: parsing database URL: %v", err)
+	}
+
+	db, err := pop.NewConnection(&pop.ConnectionDetails{
+		Dialect: "postgres",
+		URL:     databaseURL,
+	})
+	if err != nil {
+		t.Fatalf("gotruetest: opening database connection: %v", err)
+	}
+	if err := db.Open(); err != nil {
+		t.Fatalf("gotruetest: opening database connection: %v", err)
+	}
+
+	schema := "gotruetest_" + strings.ReplaceAll(uuid.Must(uuid.NewV4()).String(), "-", "")
+	if err := db.RawQuery(fmt.Sprintf("create schema %q", schema)).Exec(); err != nil {
+		db.Close()
+		t.Fatalf("gotruetest: creating schema: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := db.RawQuery(fmt.Sprintf("drop schema %q cascade", schema)).Exec(); err != nil {
+			t.Errorf("gotruetest: dropping schema %s: %v", schema, err)
+		}
+		db.Close()
+	})
+
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+
+	return u.String(), schema
+}
@@ -0,0 +1,230 @@
+// Package gotruetest runs the API in-process for integration tests of
+// services using it, against a database schema of its own and with the
+// emails it sends captured instead of sent:
+//
+//	func TestSignIn(t *testing.T) {
+//		srv := gotruetest.New(t, gotruetest.Options{})
+//		srv.CreateUser(t, "user@example.com", "password")
+//		session := srv.SignIn(t, "user@example.com", "password")
+//		// call your service with session.AccessToken, signed with srv.JWTSecret
+//	}
+//
+// A PostgreSQL database is required, see Options.DatabaseURL.
+package gotruetest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/supabase/auth/internal/api"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/crypto"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// Options configure the Server created by New.
+type Options struct {
+	// DatabaseURL is the PostgreSQL database the Server creates its schema
+	// in. The user needs to be able to create schemas. Defaults to the
+	// GOTRUE_DB_DATABASE_URL or the DATABASE_URL environment variable.
+	DatabaseURL string
+
+	// Env sets configuration options by their environment variable, e.g.
+	// GOTRUE_MAILER_AUTOCONFIRM. They take precedence over the defaults of
+	// the Server.
+	Env map[string]string
+}
+
+// Server is the API served by an httptest.Server.
+type Server struct {
+	// URL is the base URL of the API, e.g. http://127.0.0.1:12345.
+	URL string
+
+	// JWTSecret is the secret the access tokens are signed with.
+	JWTSecret string
+
+	server  *httptest.Server
+	mailbox *mailbox
+}
+
+// User is a user created by Server.CreateUser.
+type User struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+	Role  string `json:"role"`
+}
+
+// Session holds the tokens returned by Server.SignIn.
+type Session struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	User         User   `json:"user"`
+}
+
+// New starts a Server, which is closed and has its database schema dropped
+// when the test finishes. The configuration is loaded from the environment
+// with t.Setenv, so New can't be used in parallel tests.
+func New(t testing.TB, opts Options) *Server {
+	t.Helper()
+
+	databaseURL := opts.DatabaseURL
+	if databaseURL == "" {
+		databaseURL = os.Getenv("GOTRUE_DB_DATABASE_URL")
+	}
+	if databaseURL == "" {
+		databaseURL = os.Getenv("DATABASE_URL")
+	}
+	if databaseURL == "" {
+		t.Fatal("gotruetest: no database URL, set Options.DatabaseURL or DATABASE_URL")
+	}
+
+	schemaURL, schema := createSchema(t, databaseURL)
+
+	srv := &Server{
+		JWTSecret: crypto.SecureToken(32),
+		server:    httptest.NewUnstartedServer(nil),
+		mailbox:   &mailbox{},
+	}
+	srv.URL = "http://" + srv.server.Listener.Addr().String()
+
+	env := map[string]string{
+		"GOTRUE_DB_DRIVER":             "postgres",
+		"GOTRUE_DB_DATABASE_URL":       schemaURL,
+		"DB_NAMESPACE":                 schema,
+		"GOTRUE_DB_MIGRATIONS_PATH":    "",
+		"GOTRUE_SITE_URL":              srv.URL,
+		"API_EXTERNAL_URL":             srv.URL,
+		"GOTRUE_JWT_SECRET":            srv.JWTSecret,
+		"GOTRUE_SMTP_MAX_FREQUENCY":    "1ns",
+		"GOTRUE_RATE_LIMIT_EMAIL_SENT": "1000000",
+		"GOTRUE_RATE_LIMIT_SMS_SENT":   "1000000",
+	}
+	for key, value := range opts.Env {
+		env[key] = value
+	}
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+
+	config, err := conf.LoadGlobal("")
+	if err != nil {
+		t.Fatalf("gotruetest: loading configuration: %v", err)
+	}
+	srv.JWTSecret = config.JWT.Secret
+
+	if err := storage.Migrate(config); err != nil {
+		t.Fatalf("gotruetest: %v", err)
+	}
+
+	db, err := storage.Dial(config)
+	if err != nil {
+		t.Fatalf("gotruetest: opening database connection: %v", err)
+	}
+
+	a := api.NewAPIWithVersion(context.Background(), config, db, "gotruetest", api.WithMailClient(srv.mailbox))
+	srv.server.Config.Handler = a.Handler()
+	srv.server.Start()
+
+	t.Cleanup(func() {
+		srv.server.Close()
+		db.Close()
+	})
+
+	return srv
+}
+
+// Client returns an HTTP client for the Server.
+func (s *Server) Client() *http.Client {
+	return s.server.Client()
+}
+
+// Emails returns the emails sent so far, oldest first.
+func (s *Server) Emails() []Email {
+	return s.mailbox.all()
+}
+
+// AdminToken returns an access token with the service_role role, which is
+// allowed to call the /admin endpoints.
+func (s *Server) AdminToken(t testing.TB) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &api.AccessTokenClaims{
+		Role: "service_role",
+	}).SignedString([]byte(s.JWTSecret))
+	if err != nil {
+		t.Fatalf("gotruetest: signing admin token: %v", err)
+	}
+	return token
+}
+
+// CreateUser creates a user with a confirmed email address.
+func (s *Server) CreateUser(t testing.TB, email, password string) *User {
+	t.Helper()
+
+	user := &User{}
+	s.do(t, http.MethodPost, "/admin/users", s.AdminToken(t), map[string]interface{}{
+		"email":         email,
+		"password":      password,
+		"email_confirm": true,
+	}, user)
+	return user
+}
+
+// SignIn signs in with the password grant and returns the session.
+func (s *Server) SignIn(t testing.TB, email, password string) *Session {
+	t.Helper()
+
+	session := &Session{}
+	s.do(t, http.MethodPost, "/token?grant_type=password", "", map[string]interface{}{
+		"email":    email,
+		"password": password,
+	}, session)
+	return session
+}
+
+// do sends a JSON request to the API and decodes the response into out,
+// failing the test unless the response is a 200.
+func (s *Server) do(t testing.TB, method, path, token string, body, out interface{}) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
+		t.Fatalf("gotruetest: encoding request: %v", err)
+	}
+
+	req, err := http.NewRequest(method, s.URL+path, &buf)
+	if err != nil {
+		t.Fatalf("gotruetest: creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		t.Fatalf("gotruetest: %s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("gotruetest: %s %s: reading response: %v", method, path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("gotruetest: %s %s: unexpected status %d: %s", method, path, resp.StatusCode, data)
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		t.Fatalf("gotruetest: %s %s: decoding response: %v", method, path, err)
+	}
+}
@@ -0,0 +1,71 @@
+package gotruetest
+
+import (
+	"bytes"
+	"html/template"
+	"sync"
+)
+
+// Email is an email that was sent by the API.
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+
+	// Data holds the values the templates were rendered with, such as the
+	// Token and the ConfirmationURL.
+	Data map[string]interface{}
+}
+
+// mailbox captures the emails sent by the API instead of sending them.
+type mailbox struct {
+	mu     sync.Mutex
+	emails []Email
+}
+
+// Mail renders the subject and the body the same way the SMTP client does.
+// Templates configured with MAILER_TEMPLATES_* aren't fetched, the default
+// template is used instead.
+func (m *mailbox) Mail(to, subjectTemplate, templateURL, defaultTemplate string, templateData map[string]interface{}) error {
+	subject, err := render("Subject", subjectTemplate, templateData)
+	if err != nil {
+		return err
+	}
+
+	body, err := render("Body", defaultTemplate, templateData)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.emails = append(m.emails, Email{
+		To:      to,
+		Subject: subject,
+		Body:    body,
+		Data:    templateData,
+	})
+
+	return nil
+}
+
+func (m *mailbox) all() []Email {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Email(nil), m.emails...)
+}
+
+func render(name, text string, data map[string]interface{}) (string, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,95 @@
+// Command openapi2json converts the OpenAPI specification from YAML to the
+// JSON document served by the API at /openapi.json.
+//
+// Usage: openapi2json <input.yaml> <output.json>
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+func main() {
+	if len(os.Args) != 3 {
+		fmt.Fprintln(os.Stderr, "usage: openapi2json <input.yaml> <output.json>")
+		os.Exit(2)
+	}
+
+	if err := convert(os.Args[1], os.Args[2]); err != nil {
+		fmt.Fprintf(os.Stderr, "openapi2json: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func convert(input, output string) error {
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return err
+	}
+
+	var spec interface{}
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return fmt.Errorf("parsing %s: %w", input, err)
+	}
+
+	// YAML allows non-string keys, e.g. the status codes of responses,
+	// which JSON doesn't
+	spec, err = stringKeys(spec)
+	if err != nil {
+		return err
+	}
+
+	out, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(output, append(out, '\n'), 0644) // #nosec G306
+}
+
+func stringKeys(value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			converted, err := stringKeys(item)
+			if err != nil {
+				return nil, err
+			}
+			value[key] = converted
+		}
+		return value, nil
+
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			switch key.(type) {
+			case string, int, bool:
+			default:
+				return nil, fmt.Errorf("unsupported key %v of type %T", key, key)
+			}
+
+			item, err := stringKeys(item)
+			if err != nil {
+				return nil, err
+			}
+			converted[fmt.Sprint(key)] = item
+		}
+		return converted, nil
+
+	case []interface{}:
+		for i, item := range value {
+			converted, err := stringKeys(item)
+			if err != nil {
+				return nil, err
+			}
+			value[i] = converted
+		}
+		return value, nil
+
+	default:
+		return value, nil
+	}
+}
@@ -187,14 +187,16 @@ func (a *API) adminUserUpdate(w http.ResponseWriter, r *http.Request) error {
 	}
 
 	err = db.Transaction(func(tx *storage.Connection) error {
-		if params.Role != "" {
-			if terr := user.SetRole(tx, params.Role); terr != nil {
+		// confirming the email applies the role of a pending invite, so
+		// it comes first to not override the role set by the admin
+		if params.EmailConfirm {
+			if terr := user.Confirm(tx); terr != nil {
 				return terr
 			}
 		}
 
-		if params.EmailConfirm {
-			if terr := user.Confirm(tx); terr != nil {
+		if params.Role != "" {
+			if terr := user.SetRole(tx, params.Role); terr != nil {
 				return terr
 			}
 		}
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -815,3 +817,110 @@ func (ts *AdminTestSuite) TestAdminUserUpdateFactor() {
 	}
 
 }
+
+func (ts *AdminTestSuite) TestAdminApproveSignup() {
+	ts.Config.SignupApproval.Enabled = true
+	ts.Config.Mailer.Autoconfirm = true
+	defer func() {
+		ts.Config.SignupApproval.Enabled = false
+		ts.Config.Mailer.Autoconfirm = false
+	}()
+
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+		"email":    "pending@example.com",
+		"password": "test123",
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/signup", &buffer)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	// no session is issued while the signup is pending approval
+	signup := map[string]interface{}{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&signup))
+	assert.NotContains(ts.T(), signup, "access_token")
+	assert.NotEmpty(ts.T(), signup["approval_requested_at"])
+
+	login := func() int {
+		var buffer bytes.Buffer
+		require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+			"email":    "pending@example.com",
+			"password": "test123",
+		}))
+		req := httptest.NewRequest(http.MethodPost, "/token?grant_type=password", &buffer)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		ts.API.handler.ServeHTTP(w, req)
+		return w.Code
+	}
+	require.Equal(ts.T(), http.StatusForbidden, login())
+
+	req = httptest.NewRequest(http.MethodGet, "/admin/approvals", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+	w = httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	data := AdminListUsersResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+	require.Len(ts.T(), data.Users, 1)
+	u := data.Users[0]
+
+	req = httptest.NewRequest(http.MethodPost, fmt.Sprintf("/admin/approvals/%s", u.ID), nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+	w = httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	require.Equal(ts.T(), http.StatusOK, login())
+
+	// approving twice is not possible
+	req = httptest.NewRequest(http.MethodPost, fmt.Sprintf("/admin/approvals/%s", u.ID), nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+	w = httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusNotFound, w.Code)
+}
+
+func (ts *AdminTestSuite) TestAdminConfigReload() {
+	testEnv, err := os.ReadFile(apiTestConfig)
+	require.NoError(ts.T(), err)
+
+	configFile := filepath.Join(ts.T().TempDir(), "test.env")
+	ts.API.SetConfigFile(configFile)
+	defer ts.API.SetConfigFile("")
+	defer os.Unsetenv("GOTRUE_MAILER_SUBJECTS_INVITE")
+	defer os.Unsetenv("GOTRUE_JWT_EXP")
+	defer os.Unsetenv("GOTRUE_RATE_LIMIT_TOKEN_REFRESH")
+	tokenLimiter := ts.API.reloader.limiters["token"]
+	require.NotNil(ts.T(), tokenLimiter)
+
+	reload := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/admin/config/reload", nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+		w := httptest.NewRecorder()
+		ts.API.handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	require.NoError(ts.T(), os.WriteFile(configFile, append(testEnv, []byte("\nGOTRUE_MAILER_SUBJECTS_INVITE=\"Join us\"\n")...), 0600))
+	require.Equal(ts.T(), http.StatusOK, reload())
+
+	reloaded := ts.API.reloader.current.Load()
+	require.NotEqual(ts.T(), ts.API, reloaded)
+	require.Equal(ts.T(), "Join us", reloaded.config.Mailer.Subjects.Invite)
+
+	// rate limiters are kept unless their limit changed
+	require.Same(ts.T(), tokenLimiter, ts.API.reloader.limiters["token"])
+	require.NoError(ts.T(), os.WriteFile(configFile, append(testEnv, []byte("\nGOTRUE_RATE_LIMIT_TOKEN_REFRESH=10\n")...), 0600))
+	require.Equal(ts.T(), http.StatusOK, reload())
+	require.NotSame(ts.T(), tokenLimiter, ts.API.reloader.limiters["token"])
+	reloaded = ts.API.reloader.current.Load()
+
+	// an invalid configuration keeps the current one
+	require.NoError(ts.T(), os.WriteFile(configFile, append(testEnv, []byte("\nGOTRUE_JWT_EXP=soon\n")...), 0600))
+	require.Equal(ts.T(), http.StatusInternalServerError, reload())
+	require.Equal(ts.T(), reloaded, ts.API.reloader.current.Load())
+}
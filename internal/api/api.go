@@ -6,8 +6,6 @@ import (
 	"regexp"
 	"time"
 
-	"github.com/didip/tollbooth/v5"
-	"github.com/didip/tollbooth/v5/limiter"
 	"github.com/go-chi/chi"
 	"github.com/rs/cors"
 	"github.com/sebest/xff"
@@ -31,12 +29,27 @@ var bearerRegexp = regexp.MustCompile(`^(?:B|b)earer (\S+$)`)
 // API is the main REST API
 type API struct {
 	handler http.Handler
+	routes  chi.Routes
 	db      *storage.Connection
 	config  *conf.GlobalConfiguration
 	version string
 
 	hibpClient *hibp.PwnedClient
 
+	reloader *configReloader
+
+	// mailClient replaces the SMTP client of the mailer when set
+	mailClient mailer.MailClient
+
+	// replica serves reads that can tolerate replication lag when set
+	replica *storage.Connection
+
+	// middlewares run before every request is routed
+	middlewares []func(http.Handler) http.Handler
+
+	// storageHooks are called in the transactions of hook events
+	storageHooks []StorageHook
+
 	// overrideTime can be used to override the clock used by handlers. Should only be used in tests!
 	overrideTime func() time.Time
 }
@@ -49,6 +62,41 @@ func (a *API) Now() time.Time {
 	return time.Now()
 }
 
+// Option customizes the API created by NewAPIWithVersion.
+type Option func(*API)
+
+// WithMailClient makes the API send emails with client instead of the
+// configured SMTP server, e.g. to capture them in tests.
+func WithMailClient(client mailer.MailClient) Option {
+	return func(a *API) {
+		a.mailClient = client
+	}
+}
+
+// WithReadReplica makes the API read from replica where replication lag is
+// tolerable, such as when looking up a refresh token before locking it.
+func WithReadReplica(replica *storage.Connection) Option {
+	return func(a *API) {
+		a.replica = replica
+	}
+}
+
+// WithMiddleware runs middleware before every request is routed, after the
+// request ID is set and panics are recovered from.
+func WithMiddleware(middleware func(http.Handler) http.Handler) Option {
+	return func(a *API) {
+		a.middlewares = append(a.middlewares, middleware)
+	}
+}
+
+// WithStorageHook calls hook in the transaction of every hook event, such as
+// a signup, see StorageHook.
+func WithStorageHook(hook StorageHook) Option {
+	return func(a *API) {
+		a.storageHooks = append(a.storageHooks, hook)
+	}
+}
+
 // NewAPI instantiates a new REST API
 func NewAPI(globalConfig *conf.GlobalConfiguration, db *storage.Connection) *API {
 	return NewAPIWithVersion(context.Background(), globalConfig, db, defaultVersion)
@@ -69,8 +117,20 @@ func (a *API) deprecationNotices(ctx context.Context) {
 }
 
 // NewAPIWithVersion creates a new REST API using the specified version
-func NewAPIWithVersion(ctx context.Context, globalConfig *conf.GlobalConfiguration, db *storage.Connection, version string) *API {
-	api := &API{config: globalConfig, db: db, version: version}
+func NewAPIWithVersion(ctx context.Context, globalConfig *conf.GlobalConfiguration, db *storage.Connection, version string, opts ...Option) *API {
+	reloader := &configReloader{baseCtx: ctx, opts: opts}
+	api := newAPI(reloader, globalConfig, db, version)
+	reloader.current.Store(api)
+	return api
+}
+
+// newAPI creates an API swapped in by reloader.
+func newAPI(reloader *configReloader, globalConfig *conf.GlobalConfiguration, db *storage.Connection, version string) *API {
+	ctx := reloader.baseCtx
+	api := &API{config: globalConfig, db: db, version: version, reloader: reloader}
+	for _, opt := range reloader.opts {
+		opt(api)
+	}
 
 	if api.config.Password.HIBP.Enabled {
 		httpClient := &http.Client{
@@ -111,6 +171,14 @@ func NewAPIWithVersion(ctx context.Context, globalConfig *conf.GlobalConfigurati
 	r.UseBypass(xffmw.Handler)
 	r.Use(recoverer)
 
+	for _, middleware := range api.middlewares {
+		r.UseBypass(middleware)
+	}
+
+	if len(api.storageHooks) > 0 {
+		r.UseBypass(api.withStorageHooks)
+	}
+
 	if globalConfig.DB.CleanupEnabled {
 		cleanup := &models.Cleanup{
 			SessionTimebox:           globalConfig.Sessions.Timebox,
@@ -123,6 +191,9 @@ func NewAPIWithVersion(ctx context.Context, globalConfig *conf.GlobalConfigurati
 	}
 
 	r.Get("/health", api.HealthCheck)
+	r.Get("/healthz", api.HealthCheck)
+	r.Get("/readyz", api.ReadinessCheck)
+	r.Get("/openapi.json", api.OpenAPISpec)
 
 	r.Route("/callback", func(r *router) {
 		r.UseBypass(logger)
@@ -152,32 +223,51 @@ func NewAPIWithVersion(ctx context.Context, globalConfig *conf.GlobalConfigurati
 
 		r.With(api.limitHandler(
 			// Allow requests at the specified rate per 5 minutes.
-			tollbooth.NewLimiter(api.config.RateLimitTokenRefresh/(60*5), &limiter.ExpirableOptions{
-				DefaultExpirationTTL: time.Hour,
-			}).SetBurst(30),
+			api.rateLimiter("token", api.config.RateLimitTokenRefresh/(60*5), 30, time.Hour),
 		)).With(api.verifyCaptcha).Post("/token", api.Token)
 
 		r.With(api.limitHandler(
 			// Allow requests at the specified rate per 5 minutes.
-			tollbooth.NewLimiter(api.config.RateLimitVerify/(60*5), &limiter.ExpirableOptions{
-				DefaultExpirationTTL: time.Hour,
-			}).SetBurst(30),
+			api.rateLimiter("verify", api.config.RateLimitVerify/(60*5), 30, time.Hour),
 		)).Route("/verify", func(r *router) {
 			r.Get("/", api.Verify)
 			r.Post("/", api.Verify)
 		})
 
-		r.With(api.requireAuthentication).Post("/logout", api.Logout)
+		r.Route("/device", func(r *router) {
+			r.Use(api.requireDeviceAuthorizationEnabled)
+			r.With(api.limitHandler(
+				// Allow requests at the specified rate per 5 minutes.
+				api.rateLimiter("device_code", api.config.RateLimitVerify/(60*5), 30, time.Hour),
+			)).Post("/code", api.DeviceAuthorization)
+			r.With(api.requireAuthentication).Post("/verify", api.DeviceVerify)
+		})
+
+		r.Route("/oauth", func(r *router) {
+			r.Use(api.requireOAuthServerEnabled)
+			r.Get("/authorize", api.OAuthServerAuthorize)
+
+			r.With(api.requireAuthentication).Route("/authorizations/{authorization_id}", func(r *router) {
+				r.Get("/", api.OAuthServerGetAuthorization)
+				r.Post("/consent", api.OAuthServerConsent)
+			})
+		})
+
+		r.With(api.requireOAuthServerEnabled).With(api.requireUserInfoAuthentication).Get("/userinfo", api.UserInfo)
+		r.With(api.requireOAuthServerEnabled).Get("/.well-known/openid-configuration", api.OpenIDConfiguration)
+
+		r.With(api.requireProfileAuthentication).Post("/logout", api.Logout)
 
 		r.With(api.requireAuthentication).Route("/reauthenticate", func(r *router) {
 			r.Get("/", api.Reauthenticate)
 		})
 
-		r.With(api.requireAuthentication).Route("/user", func(r *router) {
+		r.With(api.requireProfileAuthentication).Route("/user", func(r *router) {
 			r.Get("/", api.UserGet)
 			r.With(sharedLimiter).Put("/", api.UserUpdate)
 
 			r.Route("/identities", func(r *router) {
+				r.Use(api.requireCompleteProfile)
 				r.Use(api.requireManualLinkingEnabled)
 				r.Get("/authorize", api.LinkIdentity)
 				r.Delete("/{identity_id}", api.DeleteIdentity)
@@ -190,13 +280,9 @@ func NewAPIWithVersion(ctx context.Context, globalConfig *conf.GlobalConfigurati
 				r.Use(api.loadFactor)
 
 				r.With(api.limitHandler(
-					tollbooth.NewLimiter(api.config.MFA.RateLimitChallengeAndVerify/60, &limiter.ExpirableOptions{
-						DefaultExpirationTTL: time.Minute,
-					}).SetBurst(30))).Post("/verify", api.VerifyFactor)
+					api.rateLimiter("mfa_verify", api.config.MFA.RateLimitChallengeAndVerify/60, 30, time.Minute))).Post("/verify", api.VerifyFactor)
 				r.With(api.limitHandler(
-					tollbooth.NewLimiter(api.config.MFA.RateLimitChallengeAndVerify/60, &limiter.ExpirableOptions{
-						DefaultExpirationTTL: time.Minute,
-					}).SetBurst(30))).Post("/challenge", api.ChallengeFactor)
+					api.rateLimiter("mfa_challenge", api.config.MFA.RateLimitChallengeAndVerify/60, 30, time.Minute))).Post("/challenge", api.ChallengeFactor)
 				r.Delete("/", api.UnenrollFactor)
 
 			})
@@ -206,9 +292,7 @@ func NewAPIWithVersion(ctx context.Context, globalConfig *conf.GlobalConfigurati
 			r.Use(api.requireSAMLEnabled)
 			r.With(api.limitHandler(
 				// Allow requests at the specified rate per 5 minutes.
-				tollbooth.NewLimiter(api.config.RateLimitSso/(60*5), &limiter.ExpirableOptions{
-					DefaultExpirationTTL: time.Hour,
-				}).SetBurst(30),
+				api.rateLimiter("sso", api.config.RateLimitSso/(60*5), 30, time.Hour),
 			)).With(api.verifyCaptcha).Post("/", api.SingleSignOn)
 
 			r.Route("/saml", func(r *router) {
@@ -216,9 +300,7 @@ func NewAPIWithVersion(ctx context.Context, globalConfig *conf.GlobalConfigurati
 
 				r.With(api.limitHandler(
 					// Allow requests at the specified rate per 5 minutes.
-					tollbooth.NewLimiter(api.config.SAML.RateLimitAssertion/(60*5), &limiter.ExpirableOptions{
-						DefaultExpirationTTL: time.Hour,
-					}).SetBurst(30),
+					api.rateLimiter("saml_acs", api.config.SAML.RateLimitAssertion/(60*5), 30, time.Hour),
 				)).Post("/acs", api.SAMLACS)
 			})
 		})
@@ -251,8 +333,35 @@ func NewAPIWithVersion(ctx context.Context, globalConfig *conf.GlobalConfigurati
 				})
 			})
 
+			r.Route("/invites", func(r *router) {
+				r.Get("/", api.adminInvites)
+				r.With(api.loadUser).Delete("/{user_id}", api.adminInviteRevoke)
+			})
+
+			r.Route("/approvals", func(r *router) {
+				r.Get("/", api.adminApprovals)
+				r.With(api.loadUser).Post("/{user_id}", api.adminApprove)
+			})
+
 			r.Post("/generate_link", api.adminGenerateLink)
 
+			r.Post("/config/reload", api.adminConfigReload)
+
+			r.Route("/oauth", func(r *router) {
+				r.Use(api.requireOAuthClientsEnabled)
+				r.Route("/clients", func(r *router) {
+					r.Get("/", api.adminOAuthClientsList)
+					r.Post("/", api.adminOAuthClientsCreate)
+
+					r.Route("/{client_id}", func(r *router) {
+						r.Use(api.loadOAuthClient)
+
+						r.Get("/", api.adminOAuthClientsGet)
+						r.Delete("/", api.adminOAuthClientsDelete)
+					})
+				})
+			})
+
 			r.Route("/sso", func(r *router) {
 				r.Route("/providers", func(r *router) {
 					r.Get("/", api.adminSSOProvidersList)
@@ -278,6 +387,7 @@ func NewAPIWithVersion(ctx context.Context, globalConfig *conf.GlobalConfigurati
 		AllowCredentials: true,
 	})
 
+	api.routes = r.chi
 	api.handler = corsHandler.Handler(chi.ServerBaseContext(ctx, r))
 	return api
 }
@@ -288,7 +398,9 @@ type HealthCheckResponse struct {
 	Description string `json:"description"`
 }
 
-// HealthCheck endpoint indicates if the gotrue api service is available
+// HealthCheck endpoint indicates if the gotrue api service is available. It's
+// also served as /healthz for liveness probes, and doesn't check any
+// dependencies, see ReadinessCheck.
 func (a *API) HealthCheck(w http.ResponseWriter, r *http.Request) error {
 	return sendJSON(w, http.StatusOK, HealthCheckResponse{
 		Version:     a.version,
@@ -297,8 +409,29 @@ func (a *API) HealthCheck(w http.ResponseWriter, r *http.Request) error {
 	})
 }
 
+// readDB returns the read replica if there is one, or the database.
+func (a *API) readDB(ctx context.Context) *storage.Connection {
+	if a.replica != nil {
+		return a.replica.WithContext(ctx)
+	}
+	return a.db.WithContext(ctx)
+}
+
+// Handler returns the handler serving the API. It serves requests with the
+// latest configuration when the configuration is reloaded.
+func (a *API) Handler() http.Handler {
+	return a.reloader
+}
+
 // Mailer returns NewMailer with the current tenant config
 func (a *API) Mailer(ctx context.Context) mailer.Mailer {
 	config := a.config
+	if a.mailClient != nil {
+		return &mailer.TemplateMailer{
+			SiteURL: config.SiteURL,
+			Config:  config,
+			Mailer:  a.mailClient,
+		}
+	}
 	return mailer.NewMailer(config)
 }
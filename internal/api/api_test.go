@@ -1,12 +1,18 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 	"github.com/supabase/auth/internal/conf"
 	"github.com/supabase/auth/internal/crypto"
+	"github.com/supabase/auth/internal/models"
 	"github.com/supabase/auth/internal/storage"
 	"github.com/supabase/auth/internal/storage/test"
 )
@@ -55,3 +61,94 @@ func TestEmailEnabledByDefault(t *testing.T) {
 
 	require.True(t, api.config.External.Email.Enabled)
 }
+
+// testMailClient drops all emails.
+type testMailClient struct{}
+
+func (c *testMailClient) Mail(to, subjectTemplate, templateURL, defaultTemplate string, templateData map[string]interface{}) error {
+	return nil
+}
+
+func TestReadinessCheck(t *testing.T) {
+	api, config, err := setupAPIForTest()
+	require.NoError(t, err)
+	defer api.db.Close()
+
+	config.External.Email.Enabled = true
+	config.External.Phone.Enabled = false
+	config.SMTP.Host = ""
+
+	readyz := func() (int, ReadinessCheckResponse) {
+		req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+		w := httptest.NewRecorder()
+		api.handler.ServeHTTP(w, req)
+
+		resp := ReadinessCheckResponse{}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+		return w.Code, resp
+	}
+
+	// a missing SMTP host doesn't fail the check
+	code, resp := readyz()
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, readinessCheckOK, resp.Checks["database"].Status)
+	require.Equal(t, readinessCheckOK, resp.Checks["migrations"].Status)
+	require.Equal(t, readinessCheckWarning, resp.Checks["smtp"].Status)
+	require.Equal(t, readinessCheckSkipped, resp.Checks["sms"].Status)
+
+	config.SMTP.Host = "localhost"
+	code, resp = readyz()
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, readinessCheckOK, resp.Status)
+	require.Equal(t, readinessCheckOK, resp.Checks["smtp"].Status)
+
+	// SMTP isn't used with a mail client
+	config.SMTP.Host = ""
+	api.mailClient = &testMailClient{}
+	code, resp = readyz()
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, readinessCheckSkipped, resp.Checks["smtp"].Status)
+
+	// a missing migrations directory fails the check
+	config.DB.MigrationsPath = t.TempDir()
+	code, resp = readyz()
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, readinessCheckFailed, resp.Checks["migrations"].Status)
+}
+
+func TestStorageHook(t *testing.T) {
+	api, config, err := setupAPIForTest()
+	require.NoError(t, err)
+	defer api.db.Close()
+	require.NoError(t, models.TruncateAll(api.db))
+
+	var events []HookEvent
+	hooked := NewAPIWithVersion(context.Background(), config, api.db, apiTestVersion, WithStorageHook(func(ctx context.Context, tx *storage.Connection, event HookEvent, user *models.User) error {
+		if user.GetEmail() == "rejected@example.com" {
+			return errors.New("rejected by the storage hook")
+		}
+		events = append(events, event)
+		return nil
+	}))
+
+	signup := func(email string) int {
+		var buffer bytes.Buffer
+		require.NoError(t, json.NewEncoder(&buffer).Encode(map[string]interface{}{
+			"email":    email,
+			"password": "test123",
+		}))
+		req := httptest.NewRequest(http.MethodPost, "/signup", &buffer)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		hooked.handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusOK, signup("test@example.com"))
+	require.Contains(t, events, HookEvent(ValidateEvent))
+
+	// an error of the hook rolls back the signup
+	require.Equal(t, http.StatusInternalServerError, signup("rejected@example.com"))
+	_, err = models.FindUserByEmailAndAudience(api.db, "rejected@example.com", config.JWT.Aud)
+	require.True(t, models.IsNotFoundError(err))
+}
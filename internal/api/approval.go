@@ -0,0 +1,97 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/observability"
+	"github.com/supabase/auth/internal/storage"
+)
+
+const signupPendingApprovalMsg = "Signup is pending approval"
+
+// requestSignupApproval holds a new signup for approval by an admin when
+// signup approval is enabled, and notifies the configured operators.
+func (a *API) requestSignupApproval(r *http.Request, tx *storage.Connection, user *models.User) error {
+	config := a.config
+	if !config.SignupApproval.Enabled {
+		return nil
+	}
+
+	if err := user.RequestApproval(tx); err != nil {
+		return internalServerError("Database error updating user").WithInternalError(err)
+	}
+
+	// a failure to notify an operator shouldn't fail the signup, the
+	// signup still shows up in the list of pending approvals
+	mailer := a.Mailer(r.Context())
+	for _, operatorEmail := range config.SignupApproval.OperatorEmails {
+		if err := mailer.SignupApprovalRequestedMail(user, operatorEmail); err != nil {
+			observability.GetLogEntry(r).WithError(err).Warnf("Unable to notify operator %v of pending signup approval", operatorEmail)
+		}
+	}
+
+	return nil
+}
+
+// adminApprovals lists the signups that are waiting for approval.
+func (a *API) adminApprovals(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	aud := a.requestAud(ctx, r)
+
+	pageParams, err := paginate(r)
+	if err != nil {
+		return badRequestError("Bad Pagination Parameters: %v", err)
+	}
+
+	users, err := models.FindUsersPendingApprovalInAudience(db, aud, pageParams)
+	if err != nil {
+		return internalServerError("Database error finding users").WithInternalError(err)
+	}
+	addPaginationHeaders(w, r, pageParams)
+
+	return sendJSON(w, http.StatusOK, AdminListUsersResponse{
+		Users: users,
+		Aud:   aud,
+	})
+}
+
+// adminApprove approves a signup that is waiting for approval so the user
+// can sign in.
+func (a *API) adminApprove(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	config := a.config
+	user := getUser(ctx)
+	adminUser := getAdminUser(ctx)
+
+	if !user.IsPendingApproval() {
+		return notFoundError("Signup pending approval not found")
+	}
+
+	err := a.db.Transaction(func(tx *storage.Connection) error {
+		if terr := user.Approve(tx); terr != nil {
+			return internalServerError("Database error updating user").WithInternalError(terr)
+		}
+
+		if terr := models.NewAuditLogEntry(r, tx, adminUser, models.UserApprovedAction, "", map[string]interface{}{
+			"user_id":    user.ID,
+			"user_email": user.Email,
+			"user_phone": user.Phone,
+		}); terr != nil {
+			return internalServerError("Error recording audit log entry").WithInternalError(terr)
+		}
+
+		if config.SignupApproval.NotifyApplicants && user.GetEmail() != "" {
+			if terr := a.Mailer(ctx).SignupApprovedMail(user); terr != nil {
+				return internalServerError("Error sending approval email").WithInternalError(terr)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusOK, user)
+}
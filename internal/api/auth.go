@@ -14,6 +14,39 @@ import (
 
 // requireAuthentication checks incoming requests for tokens presented using the Authorization header
 func (a *API) requireAuthentication(w http.ResponseWriter, r *http.Request) (context.Context, error) {
+	ctx, err := a.requireProfileAuthentication(w, r)
+	if err != nil {
+		return ctx, err
+	}
+	return a.requireCompleteProfile(w, r.WithContext(ctx))
+}
+
+// requireProfileAuthentication is like requireAuthentication, but also
+// accepts access tokens limited to updating the profile.
+func (a *API) requireProfileAuthentication(w http.ResponseWriter, r *http.Request) (context.Context, error) {
+	ctx, err := a.authenticate(w, r)
+	if err != nil {
+		return ctx, err
+	}
+	if claims := getClaims(ctx); claims != nil && claims.ClientID != "" {
+		return nil, forbiddenError(oauthClientTokenMsg)
+	}
+	return ctx, nil
+}
+
+// requireUserInfoAuthentication is like requireAuthentication, but also
+// accepts access tokens issued to third-party OAuth clients.
+func (a *API) requireUserInfoAuthentication(w http.ResponseWriter, r *http.Request) (context.Context, error) {
+	ctx, err := a.authenticate(w, r)
+	if err != nil {
+		return ctx, err
+	}
+	return a.requireCompleteProfile(w, r.WithContext(ctx))
+}
+
+// authenticate loads the user and session of the access token presented
+// using the Authorization header, regardless of its scope.
+func (a *API) authenticate(w http.ResponseWriter, r *http.Request) (context.Context, error) {
 	token, err := a.extractBearerToken(r)
 	config := a.config
 	if err != nil {
@@ -35,6 +68,16 @@ func (a *API) requireAuthentication(w http.ResponseWriter, r *http.Request) (con
 	return ctx, err
 }
 
+// requireCompleteProfile rejects access tokens of users with an incomplete
+// profile, which can only be used to update the profile.
+func (a *API) requireCompleteProfile(w http.ResponseWriter, r *http.Request) (context.Context, error) {
+	ctx := r.Context()
+	if claims := getClaims(ctx); claims != nil && claims.Scope == profileUpdateScope {
+		return nil, forbiddenError(profileIncompleteMsg)
+	}
+	return ctx, nil
+}
+
 func (a *API) requireAdmin(ctx context.Context, w http.ResponseWriter, r *http.Request) (context.Context, error) {
 	// Find the administrative user
 	claims := getClaims(ctx)
@@ -42,6 +85,12 @@ func (a *API) requireAdmin(ctx context.Context, w http.ResponseWriter, r *http.R
 		fmt.Printf("[%s] %s %s %d %s\n", time.Now().Format("2006-01-02 15:04:05"), r.Method, r.RequestURI, http.StatusForbidden, "Invalid token")
 		return nil, unauthorizedError("Invalid token")
 	}
+	if claims.Scope == profileUpdateScope {
+		return nil, forbiddenError(profileIncompleteMsg)
+	}
+	if claims.ClientID != "" {
+		return nil, forbiddenError(oauthClientTokenMsg)
+	}
 
 	adminRoles := a.config.JWT.AdminRoles
 
@@ -72,10 +121,25 @@ func (a *API) parseJWTClaims(bearer string, r *http.Request) (context.Context, e
 	token, err := p.ParseWithClaims(bearer, &AccessTokenClaims{}, func(token *jwt.Token) (interface{}, error) {
 		return []byte(config.JWT.Secret), nil
 	})
+	if err != nil && config.JWT.PreviousSecret != "" && (config.JWT.PreviousSecretExpiresAt == nil || a.Now().Before(*config.JWT.PreviousSecretExpiresAt)) {
+		// tokens signed before the secret was rotated are valid until
+		// they expire
+		if previousToken, perr := p.ParseWithClaims(bearer, &AccessTokenClaims{}, func(token *jwt.Token) (interface{}, error) {
+			return []byte(config.JWT.PreviousSecret), nil
+		}); perr == nil {
+			token, err = previousToken, nil
+		}
+	}
 	if err != nil {
 		return nil, unauthorizedError("invalid JWT: unable to parse or verify signature, %v", err)
 	}
 
+	// ID tokens issued to OAuth clients are signed with the same secret,
+	// but only prove the user's identity to the client
+	if claims, ok := token.Claims.(*AccessTokenClaims); ok && claims.AuthorizedParty != "" {
+		return nil, unauthorizedError("invalid JWT: ID tokens can't be used as access tokens")
+	}
+
 	return withToken(ctx, token), nil
 }
 
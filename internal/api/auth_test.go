@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gofrs/uuid"
 	jwt "github.com/golang-jwt/jwt"
@@ -71,6 +72,37 @@ func (ts *AuthTestSuite) TestParseJWTClaims() {
 	require.Equal(ts.T(), userJwt, token.Raw)
 }
 
+func (ts *AuthTestSuite) TestParseJWTClaimsWithPreviousSecret() {
+	ts.Config.JWT.PreviousSecret = "previous-secret"
+	defer func() {
+		ts.Config.JWT.PreviousSecret = ""
+	}()
+
+	userClaims := &AccessTokenClaims{
+		Role: "authenticated",
+	}
+	req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+
+	previousJwt, err := jwt.NewWithClaims(jwt.SigningMethodHS256, userClaims).SignedString([]byte("previous-secret"))
+	require.NoError(ts.T(), err)
+	_, err = ts.API.parseJWTClaims(previousJwt, req)
+	require.NoError(ts.T(), err)
+
+	otherJwt, err := jwt.NewWithClaims(jwt.SigningMethodHS256, userClaims).SignedString([]byte("other-secret"))
+	require.NoError(ts.T(), err)
+	_, err = ts.API.parseJWTClaims(otherJwt, req)
+	require.Error(ts.T(), err)
+
+	// the previous secret isn't accepted once it has expired
+	expiresAt := time.Now().Add(-time.Minute)
+	ts.Config.JWT.PreviousSecretExpiresAt = &expiresAt
+	defer func() {
+		ts.Config.JWT.PreviousSecretExpiresAt = nil
+	}()
+	_, err = ts.API.parseJWTClaims(previousJwt, req)
+	require.Error(ts.T(), err)
+}
+
 func (ts *AuthTestSuite) TestMaybeLoadUserOrSession() {
 	u, err := models.FindUserByEmailAndAudience(ts.API.db, "test@example.com", ts.Config.JWT.Aud)
 	require.NoError(ts.T(), err)
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+	"github.com/supabase/auth/internal/utilities"
+)
+
+// clientCredentialsReservedClaims can't be overridden by the claims
+// configured on a client.
+var clientCredentialsReservedClaims = map[string]bool{
+	"aud":       true,
+	"exp":       true,
+	"iat":       true,
+	"iss":       true,
+	"jti":       true,
+	"nbf":       true,
+	"sub":       true,
+	"role":      true,
+	"client_id": true,
+}
+
+// ClientCredentialsTokenResponse is the response of the client credentials
+// grant. No refresh token is issued, see RFC 6749 Section 4.4.3.
+type ClientCredentialsTokenResponse struct {
+	Token     string `json:"access_token"`
+	TokenType string `json:"token_type"` // Bearer
+	ExpiresIn int    `json:"expires_in"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// ClientCredentialsGrant issues an access token to a confidential client for
+// itself, with no associated user (RFC 6749 Section 4.4).
+func (a *API) ClientCredentialsGrant(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	db := a.db.WithContext(ctx)
+	config := a.config
+
+	if !config.OAuthServer.Enabled {
+		return oauthError("unsupported_grant_type", "OAuth server is disabled")
+	}
+
+	params, err := getOAuthTokenParams(r)
+	if err != nil {
+		return err
+	}
+
+	if params.ClientID == "" || params.ClientSecret == "" {
+		return oauthError("invalid_client", "Invalid client credentials")
+	}
+
+	client, err := a.authenticateOAuthClient(db, params.ClientID, params.ClientSecret)
+	if err != nil {
+		return err
+	}
+
+	if !client.IsConfidential() || !client.ClientCredentialsEnabled {
+		return oauthError("unauthorized_client", "Client is not allowed to use the client credentials grant")
+	}
+
+	var token *ClientCredentialsTokenResponse
+	err = db.Transaction(func(tx *storage.Connection) error {
+		var terr error
+		if terr = models.NewClientAuditLogEntry(r, tx, client, models.ClientCredentialsGrantedAction, utilities.GetIPAddress(r), nil); terr != nil {
+			return terr
+		}
+		token, terr = a.generateClientAccessToken(client)
+		return terr
+	})
+	if err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusOK, token)
+}
+
+func (a *API) generateClientAccessToken(client *models.OAuthClient) (*ClientCredentialsTokenResponse, error) {
+	config := a.config
+
+	issuedAt := time.Now().UTC()
+	expiresAt := issuedAt.Add(time.Second * time.Duration(config.JWT.Exp)).Unix()
+
+	role := config.OAuthServer.ClientCredentialsDefaultRole
+	if client.Role != nil {
+		role = *client.Role
+	}
+
+	claims := jwt.MapClaims{}
+	for key, value := range client.Claims {
+		if !clientCredentialsReservedClaims[key] {
+			claims[key] = value
+		}
+	}
+	claims["sub"] = client.ID.String()
+	claims["aud"] = config.JWT.Aud
+	claims["iat"] = issuedAt.Unix()
+	claims["exp"] = expiresAt
+	claims["iss"] = config.JWT.Issuer
+	claims["role"] = role
+	claims["client_id"] = client.ID.String()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	if config.JWT.KeyID != "" {
+		token.Header["kid"] = config.JWT.KeyID
+	}
+
+	signed, err := token.SignedString([]byte(config.JWT.Secret))
+	if err != nil {
+		return nil, internalServerError("Error signing access token").WithInternalError(err)
+	}
+
+	return &ClientCredentialsTokenResponse{
+		Token:     signed,
+		TokenType: "bearer",
+		ExpiresIn: config.JWT.Exp,
+		ExpiresAt: expiresAt,
+	}, nil
+}
@@ -32,6 +32,8 @@ const (
 	ssoProviderKey          = contextKey("sso_provider")
 	externalHostKey         = contextKey("external_host")
 	flowStateKey            = contextKey("flow_state_id")
+	oauthClientKey          = contextKey("oauth_client")
+	storageHooksKey         = contextKey("storage_hooks")
 )
 
 // withToken adds the JWT token to the context.
@@ -205,6 +207,21 @@ func getFunctionHooks(ctx context.Context) map[string][]string {
 	return obj.(map[string][]string)
 }
 
+// withStorageHooks adds the storage hooks to the context.
+func withStorageHooks(ctx context.Context, hooks []StorageHook) context.Context {
+	return context.WithValue(ctx, storageHooksKey, hooks)
+}
+
+// getStorageHooks reads the storage hooks from the context.
+func getStorageHooks(ctx context.Context) []StorageHook {
+	obj := ctx.Value(storageHooksKey)
+	if obj == nil {
+		return nil
+	}
+
+	return obj.([]StorageHook)
+}
+
 // withAdminUser adds the admin user to the context.
 func withAdminUser(ctx context.Context, u *models.User) context.Context {
 	return context.WithValue(ctx, adminUserKey, u)
@@ -256,6 +273,18 @@ func getSSOProvider(ctx context.Context) *models.SSOProvider {
 	return obj.(*models.SSOProvider)
 }
 
+func withOAuthClient(ctx context.Context, client *models.OAuthClient) context.Context {
+	return context.WithValue(ctx, oauthClientKey, client)
+}
+
+func getOAuthClient(ctx context.Context) *models.OAuthClient {
+	obj := ctx.Value(oauthClientKey)
+	if obj == nil {
+		return nil
+	}
+	return obj.(*models.OAuthClient)
+}
+
 func withExternalHost(ctx context.Context, u *url.URL) context.Context {
 	return context.WithValue(ctx, externalHostKey, u)
 }
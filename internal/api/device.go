@@ -0,0 +1,263 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/supabase/auth/internal/metering"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// deviceCodeGrantType is the grant type defined in RFC 8628 Section 3.4.
+const deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// DeviceAuthorizationResponse is the response of the device authorization
+// endpoint, see RFC 8628 Section 3.2.
+type DeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// DeviceVerifyParams are the parameters the device verification endpoint
+// accepts.
+type DeviceVerifyParams struct {
+	UserCode string `json:"user_code"`
+	Deny     bool   `json:"deny"`
+}
+
+// DeviceCodeGrantParams are the parameters the DeviceCodeGrant method accepts
+type DeviceCodeGrantParams struct {
+	DeviceCode   string `json:"device_code"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+}
+
+// DeviceAuthorization starts a device authorization request for a registered,
+// input-constrained client (RFC 8628 Section 3.1).
+func (a *API) DeviceAuthorization(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+
+	params, err := getOAuthTokenParams(r)
+	if err != nil {
+		return err
+	}
+
+	if params.ClientID == "" {
+		return oauthError("invalid_request", "client_id is required")
+	}
+
+	client, err := a.authenticateOAuthClient(db, params.ClientID, params.ClientSecret)
+	if err != nil {
+		return err
+	}
+
+	deviceCode, code, err := models.NewDeviceCode(client.ID, config.Device.PollingInterval)
+	if err != nil {
+		return internalServerError("Error generating device code").WithInternalError(err)
+	}
+
+	if err := db.Create(deviceCode); err != nil {
+		return internalServerError("Database error creating device code").WithInternalError(err)
+	}
+
+	verificationURIComplete, err := url.Parse(config.Device.VerificationURL)
+	if err != nil {
+		return internalServerError("Invalid device verification URL").WithInternalError(err)
+	}
+	q := verificationURIComplete.Query()
+	q.Set("user_code", deviceCode.UserCode)
+	verificationURIComplete.RawQuery = q.Encode()
+
+	return sendJSON(w, http.StatusOK, &DeviceAuthorizationResponse{
+		DeviceCode:              code,
+		UserCode:                deviceCode.UserCode,
+		VerificationURI:         config.Device.VerificationURL,
+		VerificationURIComplete: verificationURIComplete.String(),
+		ExpiresIn:               int(config.Device.ExpiryDuration.Seconds()),
+		Interval:                deviceCode.PollingInterval,
+	})
+}
+
+// DeviceVerify lets a signed in user approve or deny a pending device
+// authorization request by entering the user code shown on the device.
+func (a *API) DeviceVerify(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+	user := getUser(ctx)
+
+	params := &DeviceVerifyParams{}
+	body, err := getBodyBytes(r)
+	if err != nil {
+		return badRequestError("Could not read body").WithInternalError(err)
+	}
+
+	if err := json.Unmarshal(body, params); err != nil {
+		return badRequestError("Could not read device verification params: %v", err)
+	}
+
+	if params.UserCode == "" {
+		return unprocessableEntityError("Device verification requires a user code")
+	}
+
+	err = db.Transaction(func(tx *storage.Connection) error {
+		deviceCode, terr := models.FindDeviceCodeByUserCode(tx, params.UserCode)
+		if terr != nil {
+			if models.IsNotFoundError(terr) {
+				return notFoundError("Invalid user code")
+			}
+			return internalServerError("Database error finding device code").WithInternalError(terr)
+		}
+
+		if !deviceCode.IsPending() || deviceCode.IsExpired(config.Device.ExpiryDuration) {
+			return expiredTokenError("User code is invalid or has expired")
+		}
+
+		if params.Deny {
+			if terr := models.NewAuditLogEntry(r, tx, user, models.DeviceDeniedAction, "", nil); terr != nil {
+				return terr
+			}
+			return deviceCode.Deny(tx)
+		}
+
+		if terr := models.NewAuditLogEntry(r, tx, user, models.DeviceAuthorizedAction, "", nil); terr != nil {
+			return terr
+		}
+		return deviceCode.Approve(tx, user.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusOK, map[string]string{})
+}
+
+// DeviceCodeGrant implements the device access token request of RFC 8628
+// Section 3.4, polled by the device until the user has approved or denied it.
+func (a *API) DeviceCodeGrant(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	db := a.db.WithContext(ctx)
+	config := a.config
+
+	if !config.Device.Enabled {
+		return oauthError("unsupported_grant_type", "Device authorization is disabled")
+	}
+
+	var grantParams models.GrantParams
+	grantParams.FillGrantParams(r)
+
+	params := &DeviceCodeGrantParams{}
+	body, err := getBodyBytes(r)
+	if err != nil {
+		return badRequestError("Could not read body").WithInternalError(err)
+	}
+
+	if err := json.Unmarshal(body, params); err != nil {
+		return badRequestError("Could not read device code grant params: %v", err)
+	}
+
+	if clientID, clientSecret, ok := r.BasicAuth(); ok {
+		params.ClientID = clientID
+		params.ClientSecret = clientSecret
+	}
+
+	if params.DeviceCode == "" || params.ClientID == "" {
+		return oauthError("invalid_request", "device_code and client_id are required")
+	}
+
+	client, err := a.authenticateOAuthClient(db, params.ClientID, params.ClientSecret)
+	if err != nil {
+		return err
+	}
+
+	deviceCode, err := models.FindDeviceCodeByDeviceCode(db, params.DeviceCode)
+	if err != nil {
+		if models.IsNotFoundError(err) {
+			return oauthError("invalid_grant", "Invalid device code")
+		}
+		return internalServerError("Database error finding device code").WithInternalError(err)
+	}
+
+	if deviceCode.ClientID != client.ID {
+		return oauthError("invalid_grant", "Invalid device code")
+	}
+
+	if deviceCode.IsExpired(config.Device.ExpiryDuration) {
+		return oauthError("expired_token", "Device code has expired")
+	}
+
+	if deviceCode.IsPolledTooFrequently() {
+		if err := deviceCode.SlowDown(db); err != nil {
+			return internalServerError("Database error updating device code").WithInternalError(err)
+		}
+		return oauthError("slow_down", fmt.Sprintf("Polling too frequently, wait at least %d seconds between polls", deviceCode.PollingInterval))
+	}
+
+	switch deviceCode.Status {
+	case models.DeviceCodePending.String():
+		if err := deviceCode.UpdateLastPolledAt(db); err != nil {
+			return internalServerError("Database error updating device code").WithInternalError(err)
+		}
+		return oauthError("authorization_pending", "User has not yet approved the device")
+	case models.DeviceCodeDenied.String():
+		if err := deviceCode.Redeem(db); err != nil {
+			if models.IsNotFoundError(err) {
+				return oauthError("invalid_grant", "Invalid device code")
+			}
+			return internalServerError("Database error removing device code").WithInternalError(err)
+		}
+		return oauthError("access_denied", "User denied the device authorization request")
+	}
+
+	user, err := models.FindUserByID(db, *deviceCode.UserID)
+	if err != nil {
+		if models.IsNotFoundError(err) {
+			return oauthError("invalid_grant", "Invalid device code")
+		}
+		return internalServerError("Database error finding user").WithInternalError(err)
+	}
+
+	if user.IsBanned() {
+		return oauthError("invalid_grant", "Invalid device code")
+	}
+
+	var token *AccessTokenResponse
+	err = db.Transaction(func(tx *storage.Connection) error {
+		var terr error
+		// the device code is single-use, so remove it before the token
+		// is handed out. A concurrent request that redeemed it first makes
+		// this fail.
+		if terr = deviceCode.Redeem(tx); terr != nil {
+			if models.IsNotFoundError(terr) {
+				return oauthError("invalid_grant", "Invalid device code")
+			}
+			return internalServerError("Database error removing device code").WithInternalError(terr)
+		}
+		if terr = models.NewAuditLogEntry(r, tx, user, models.LoginAction, "", map[string]interface{}{
+			"provider":  "device_code",
+			"client_id": client.ID.String(),
+		}); terr != nil {
+			return terr
+		}
+		if terr = triggerEventHooks(ctx, tx, LoginEvent, user, config); terr != nil {
+			return terr
+		}
+		token, terr = a.issueRefreshToken(ctx, tx, user, models.DeviceCodeGrant, grantParams)
+		return terr
+	})
+	if err != nil {
+		return err
+	}
+
+	metering.RecordLogin("device_code", user.ID)
+	return sendJSON(w, http.StatusOK, token)
+}
@@ -0,0 +1,262 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+)
+
+type DeviceTestSuite struct {
+	suite.Suite
+	API    *API
+	Config *conf.GlobalConfiguration
+
+	User   *models.User
+	Client *models.OAuthClient
+}
+
+func TestDevice(t *testing.T) {
+	api, config, err := setupAPIForTestWithCallback(func(config *conf.GlobalConfiguration, conn *storage.Connection) {
+		if config != nil {
+			config.Device.Enabled = true
+		}
+	})
+	require.NoError(t, err)
+
+	ts := &DeviceTestSuite{
+		API:    api,
+		Config: config,
+	}
+	defer api.db.Close()
+
+	suite.Run(t, ts)
+}
+
+func (ts *DeviceTestSuite) SetupTest() {
+	models.TruncateAll(ts.API.db)
+
+	ts.Config.Device.PollingInterval = 0
+	ts.Config.Device.ExpiryDuration = 10 * time.Minute
+
+	u, err := models.NewUser("", "test@example.com", "password", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err, "Error creating test user model")
+	require.NoError(ts.T(), ts.API.db.Create(u), "Error saving new test user")
+	ts.User = u
+
+	client, _ := models.NewOAuthClient("Example TV", models.OAuthClientPublic, nil)
+	require.NoError(ts.T(), ts.API.db.Create(client), "Error saving new test client")
+	ts.Client = client
+}
+
+func (ts *DeviceTestSuite) startDeviceAuthorization(clientID string) *httptest.ResponseRecorder {
+	form := url.Values{}
+	if clientID != "" {
+		form.Set("client_id", clientID)
+	}
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/device/code", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (ts *DeviceTestSuite) requestDeviceCode() DeviceAuthorizationResponse {
+	w := ts.startDeviceAuthorization(ts.Client.ID.String())
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	var resp DeviceAuthorizationResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&resp))
+	return resp
+}
+
+func (ts *DeviceTestSuite) pollToken(deviceCode string) *httptest.ResponseRecorder {
+	return ts.pollTokenAsClient(deviceCode, ts.Client.ID.String())
+}
+
+func (ts *DeviceTestSuite) pollTokenAsClient(deviceCode, clientID string) *httptest.ResponseRecorder {
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(DeviceCodeGrantParams{
+		DeviceCode: deviceCode,
+		ClientID:   clientID,
+	}))
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/token?grant_type="+deviceCodeGrantType, &buffer)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (ts *DeviceTestSuite) verifyUserCode(userCode string, deny bool) *httptest.ResponseRecorder {
+	token, _, err := ts.API.generateAccessToken(context.Background(), ts.API.db, ts.User, nil, models.PasswordGrant)
+	require.NoError(ts.T(), err)
+
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(DeviceVerifyParams{
+		UserCode: userCode,
+		Deny:     deny,
+	}))
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/device/verify", &buffer)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (ts *DeviceTestSuite) requireOAuthError(w *httptest.ResponseRecorder, expected string) {
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+	data := &OAuthError{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(data))
+	require.Equal(ts.T(), expected, data.Err)
+}
+
+func (ts *DeviceTestSuite) TestDeviceAuthorizationResponse() {
+	resp := ts.requestDeviceCode()
+
+	require.NotEmpty(ts.T(), resp.DeviceCode)
+	require.Regexp(ts.T(), "^[A-Z]{4}-[A-Z]{4}$", resp.UserCode)
+	require.Equal(ts.T(), ts.Config.Device.VerificationURL, resp.VerificationURI)
+	require.Contains(ts.T(), resp.VerificationURIComplete, "user_code="+resp.UserCode)
+	require.Equal(ts.T(), 600, resp.ExpiresIn)
+}
+
+func (ts *DeviceTestSuite) TestDeviceAuthorizationRequiresClient() {
+	ts.requireOAuthError(ts.startDeviceAuthorization(""), "invalid_request")
+	ts.requireOAuthError(ts.startDeviceAuthorization("not-a-client-id"), "invalid_client")
+	ts.requireOAuthError(ts.startDeviceAuthorization(uuid.Must(uuid.NewV4()).String()), "invalid_client")
+
+	// confidential clients must authenticate
+	client, clientSecret := models.NewOAuthClient("Example CLI", models.OAuthClientConfidential, nil)
+	require.NoError(ts.T(), ts.API.db.Create(client))
+	ts.requireOAuthError(ts.startDeviceAuthorization(client.ID.String()), "invalid_client")
+
+	form := url.Values{"client_id": {client.ID.String()}, "client_secret": {clientSecret}}
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/device/code", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+}
+
+func (ts *DeviceTestSuite) TestDeviceCodeGrantApproved() {
+	resp := ts.requestDeviceCode()
+
+	ts.requireOAuthError(ts.pollToken(resp.DeviceCode), "authorization_pending")
+
+	// user codes are accepted regardless of case and separators
+	w := ts.verifyUserCode(fmt.Sprintf(" %s ", strings.ToLower(resp.UserCode[:4]+resp.UserCode[5:])), false)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	w = ts.pollToken(resp.DeviceCode)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	token := &AccessTokenResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(token))
+	require.NotEmpty(ts.T(), token.Token)
+	require.NotEmpty(ts.T(), token.RefreshToken)
+	require.Equal(ts.T(), ts.User.ID, token.User.ID)
+
+	// device codes are single-use
+	ts.requireOAuthError(ts.pollToken(resp.DeviceCode), "invalid_grant")
+}
+
+func (ts *DeviceTestSuite) TestDeviceCodeGrantOtherClient() {
+	resp := ts.requestDeviceCode()
+
+	w := ts.verifyUserCode(resp.UserCode, false)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	// the device code can only be redeemed by the client it was issued to
+	client, _ := models.NewOAuthClient("Other TV", models.OAuthClientPublic, nil)
+	require.NoError(ts.T(), ts.API.db.Create(client))
+	ts.requireOAuthError(ts.pollTokenAsClient(resp.DeviceCode, client.ID.String()), "invalid_grant")
+	ts.requireOAuthError(ts.pollTokenAsClient(resp.DeviceCode, ""), "invalid_request")
+
+	require.Equal(ts.T(), http.StatusOK, ts.pollToken(resp.DeviceCode).Code)
+}
+
+func (ts *DeviceTestSuite) TestDeviceCodeGrantDenied() {
+	resp := ts.requestDeviceCode()
+
+	w := ts.verifyUserCode(resp.UserCode, true)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	ts.requireOAuthError(ts.pollToken(resp.DeviceCode), "access_denied")
+
+	// user code can no longer be used once denied
+	w = ts.verifyUserCode(resp.UserCode, false)
+	require.Equal(ts.T(), http.StatusNotFound, w.Code)
+}
+
+func (ts *DeviceTestSuite) TestDeviceCodeGrantSlowDown() {
+	ts.Config.Device.PollingInterval = time.Minute
+	resp := ts.requestDeviceCode()
+
+	ts.requireOAuthError(ts.pollToken(resp.DeviceCode), "authorization_pending")
+	ts.requireOAuthError(ts.pollToken(resp.DeviceCode), "slow_down")
+
+	// every slow_down increases the polling interval by 5 seconds
+	deviceCode, err := models.FindDeviceCodeByDeviceCode(ts.API.db, resp.DeviceCode)
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), resp.Interval+5, deviceCode.PollingInterval)
+
+	ts.requireOAuthError(ts.pollToken(resp.DeviceCode), "slow_down")
+	deviceCode, err = models.FindDeviceCodeByDeviceCode(ts.API.db, resp.DeviceCode)
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), resp.Interval+10, deviceCode.PollingInterval)
+}
+
+func (ts *DeviceTestSuite) TestDeviceCodeGrantExpired() {
+	resp := ts.requestDeviceCode()
+	ts.Config.Device.ExpiryDuration = 0
+
+	ts.requireOAuthError(ts.pollToken(resp.DeviceCode), "expired_token")
+
+	w := ts.verifyUserCode(resp.UserCode, false)
+	require.Equal(ts.T(), http.StatusUnauthorized, w.Code)
+}
+
+func (ts *DeviceTestSuite) TestDeviceCodeGrantRedeemedConcurrently() {
+	resp := ts.requestDeviceCode()
+
+	w := ts.verifyUserCode(resp.UserCode, false)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	// both requests find the approved device code, only one may exchange it
+	var wg sync.WaitGroup
+	codes := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- ts.pollToken(resp.DeviceCode).Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	var succeeded int
+	for code := range codes {
+		if code == http.StatusOK {
+			succeeded++
+		} else {
+			require.Equal(ts.T(), http.StatusBadRequest, code)
+		}
+	}
+	require.Equal(ts.T(), 1, succeeded)
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
 	"github.com/supabase/auth/internal/observability"
 	"github.com/supabase/auth/internal/utilities"
 )
@@ -36,6 +37,10 @@ type OAuthError struct {
 	Description     string `json:"error_description,omitempty"`
 	InternalError   error  `json:"-"`
 	InternalMessage string `json:"-"`
+
+	// Factors lists the verified factors the user can verify with when
+	// the error is mfa_required.
+	Factors []models.Factor `json:"factors,omitempty"`
 }
 
 func (e *OAuthError) Error() string {
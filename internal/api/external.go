@@ -290,6 +290,10 @@ func (a *API) createAccountFromExternalIdentity(tx *storage.Connection, r *http.
 			return nil, terr
 		}
 
+		if terr = a.requestSignupApproval(r, tx, user); terr != nil {
+			return nil, terr
+		}
+
 		if terr = user.UpdateAppMetaDataProviders(tx); terr != nil {
 			return nil, terr
 		}
@@ -361,7 +365,7 @@ func (a *API) createAccountFromExternalIdentity(tx *storage.Connection, r *http.
 		if terr = user.RemoveUnconfirmedIdentities(tx, identity); terr != nil {
 			return nil, internalServerError("Error updating user").WithInternalError(terr)
 		}
-		if decision.CandidateEmail.Verified || config.Mailer.Autoconfirm {
+		if decision.CandidateEmail.Verified || config.Mailer.ShouldAutoconfirm(decision.CandidateEmail.Email) {
 			if terr := models.NewAuditLogEntry(r, tx, user, models.UserSignedUpAction, "", map[string]interface{}{
 				"provider": providerType,
 			}); terr != nil {
@@ -407,6 +411,10 @@ func (a *API) createAccountFromExternalIdentity(tx *storage.Connection, r *http.
 		}
 	}
 
+	if user.IsPendingApproval() {
+		return nil, storage.NewCommitWithError(forbiddenError(signupPendingApprovalMsg))
+	}
+
 	return user, nil
 }
 
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/supabase/auth/internal/metering"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// GrantType is implemented by custom grant types of the /token endpoint,
+// such as exchanging LDAP credentials or a legacy session cookie. A grant
+// type only authenticates the user, the session and the tokens are issued
+// the same way as for the built-in grant types.
+type GrantType interface {
+	// Authenticate returns the user to issue tokens to. Errors should be
+	// an *OAuthError, e.g. with the invalid_grant error code, or an
+	// *HTTPError. Changes made with tx are rolled back on error.
+	Authenticate(ctx context.Context, tx *storage.Connection, r *http.Request) (*models.User, error)
+}
+
+// builtinGrantTypes are handled by Token and can't be registered.
+var builtinGrantTypes = map[string]bool{
+	"password":           true,
+	"refresh_token":      true,
+	"id_token":           true,
+	"pkce":               true,
+	deviceCodeGrantType:  true,
+	"device_code":        true,
+	"authorization_code": true,
+	"client_credentials": true,
+}
+
+var (
+	grantTypesMu sync.RWMutex
+	grantTypes   = map[string]GrantType{}
+)
+
+// RegisterGrantType makes a custom grant type available as the grant_type of
+// the /token endpoint. It's meant to be called from an init function and
+// panics if the grant type is built in or already registered.
+func RegisterGrantType(name string, grantType GrantType) {
+	grantTypesMu.Lock()
+	defer grantTypesMu.Unlock()
+
+	if grantType == nil {
+		panic("api: RegisterGrantType grant type is nil")
+	}
+	if name == "" || builtinGrantTypes[name] {
+		panic(fmt.Sprintf("api: RegisterGrantType can't override grant type %q", name))
+	}
+	if _, ok := grantTypes[name]; ok {
+		panic(fmt.Sprintf("api: RegisterGrantType called twice for grant type %q", name))
+	}
+	grantTypes[name] = grantType
+}
+
+func getGrantType(name string) (GrantType, bool) {
+	grantTypesMu.RLock()
+	defer grantTypesMu.RUnlock()
+
+	grantType, ok := grantTypes[name]
+	return grantType, ok
+}
+
+// CustomGrant issues tokens to the user authenticated by a registered grant type.
+func (a *API) CustomGrant(ctx context.Context, w http.ResponseWriter, r *http.Request, name string, grantType GrantType) error {
+	db := a.db.WithContext(ctx)
+	config := a.config
+
+	var grantParams models.GrantParams
+	grantParams.FillGrantParams(r)
+
+	var user *models.User
+	var token *AccessTokenResponse
+	err := db.Transaction(func(tx *storage.Connection) error {
+		var terr error
+		user, terr = grantType.Authenticate(ctx, tx, r)
+		if terr != nil {
+			return terr
+		}
+		if user == nil || user.IsBanned() {
+			return oauthError("invalid_grant", InvalidLoginMessage)
+		}
+
+		if terr = models.NewAuditLogEntry(r, tx, user, models.LoginAction, "", map[string]interface{}{
+			"provider": name,
+		}); terr != nil {
+			return terr
+		}
+		if terr = triggerEventHooks(ctx, tx, LoginEvent, user, config); terr != nil {
+			return terr
+		}
+		token, terr = a.issueRefreshToken(ctx, tx, user, models.CustomGrant, grantParams)
+		if terr != nil {
+			return terr
+		}
+
+		if terr = a.setCookieTokens(config, token, false, w); terr != nil {
+			return internalServerError("Failed to set JWT cookie. %s", terr)
+		}
+		return nil
+	})
+	if err != nil {
+		switch err.(type) {
+		case *OAuthError, *HTTPError:
+			return err
+		default:
+			return internalServerError("Error authenticating with grant type %q", name).WithInternalError(err)
+		}
+	}
+
+	metering.RecordLogin(name, user.ID)
+	return sendJSON(w, http.StatusOK, token)
+}
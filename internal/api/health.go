@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/supabase/auth/internal/api/sms_provider"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// readinessCheckTimeout bounds how long the database checks of a readiness
+// probe may take.
+const readinessCheckTimeout = 5 * time.Second
+
+const (
+	readinessCheckOK      = "ok"
+	readinessCheckFailed  = "failed"
+	readinessCheckSkipped = "skipped"
+	readinessCheckWarning = "warning"
+)
+
+type ReadinessCheck struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type ReadinessCheckResponse struct {
+	Status string                    `json:"status"`
+	Checks map[string]ReadinessCheck `json:"checks"`
+}
+
+// ReadinessCheck endpoint indicates if the gotrue api service is ready to
+// serve requests, that is the database is reachable and migrated, and the
+// enabled email and phone providers are configured.
+func (a *API) ReadinessCheck(w http.ResponseWriter, r *http.Request) error {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessCheckTimeout)
+	defer cancel()
+
+	checks := map[string]ReadinessCheck{
+		"database":   readinessCheckResult(a.checkDatabase(ctx)),
+		"migrations": readinessCheckResult(a.checkMigrations(ctx)),
+		"smtp":       a.checkSMTP(),
+		"sms":        a.checkSMS(),
+	}
+
+	resp := ReadinessCheckResponse{
+		Status: readinessCheckOK,
+		Checks: checks,
+	}
+	status := http.StatusOK
+	for _, check := range checks {
+		if check.Status == readinessCheckFailed {
+			resp.Status = readinessCheckFailed
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	return sendJSON(w, status, resp)
+}
+
+func readinessCheckResult(err error) ReadinessCheck {
+	if err != nil {
+		return ReadinessCheck{Status: readinessCheckFailed, Error: err.Error()}
+	}
+	return ReadinessCheck{Status: readinessCheckOK}
+}
+
+func (a *API) checkDatabase(ctx context.Context) error {
+	return a.db.WithContext(ctx).RawQuery("select 1").Exec()
+}
+
+// checkMigrations checks that the latest migration Migrate applies was
+// applied.
+func (a *API) checkMigrations(ctx context.Context) error {
+	latest, err := storage.LatestMigration(a.config)
+	if err != nil {
+		return err
+	}
+
+	applied, err := models.IsMigrationApplied(a.db.WithContext(ctx), latest)
+	if err != nil {
+		return err
+	}
+	if !applied {
+		return fmt.Errorf("migration %v is not applied", latest)
+	}
+	return nil
+}
+
+// checkSMTP checks that SMTP is configured when email logins are enabled.
+// Without it emails are silently dropped, which is only a warning as it's
+// intended when emails are autoconfirmed.
+func (a *API) checkSMTP() ReadinessCheck {
+	config := a.config
+	if !config.External.Email.Enabled || a.mailClient != nil {
+		return ReadinessCheck{Status: readinessCheckSkipped}
+	}
+	if config.SMTP.Host == "" {
+		return ReadinessCheck{Status: readinessCheckWarning, Error: "SMTP host is not configured"}
+	}
+	return ReadinessCheck{Status: readinessCheckOK}
+}
+
+// checkSMS checks that the SMS provider is configured when phone logins are
+// enabled.
+func (a *API) checkSMS() ReadinessCheck {
+	config := a.config
+	if !config.External.Phone.Enabled {
+		return ReadinessCheck{Status: readinessCheckSkipped}
+	}
+	if _, err := sms_provider.GetSmsProvider(*config); err != nil {
+		return readinessCheckResult(err)
+	}
+	return ReadinessCheck{Status: readinessCheckOK}
+}
@@ -38,6 +38,12 @@ const (
 
 var defaultTimeout = time.Second * 5
 
+// StorageHook is called in the transaction of a hook event, such as a signup,
+// with the user as stored so far. It lets a program running the API
+// in-process store its own data along with the user. An error rolls back the
+// transaction and fails the request.
+type StorageHook func(ctx context.Context, tx *storage.Connection, event HookEvent, user *models.User) error
+
 type webhookClaims struct {
 	jwt.StandardClaims
 	SHA256 string `json:"sha256"`
@@ -154,6 +160,12 @@ func closeBody(rsp *http.Response) {
 }
 
 func triggerEventHooks(ctx context.Context, conn *storage.Connection, event HookEvent, user *models.User, config *conf.GlobalConfiguration) error {
+	for _, hook := range getStorageHooks(ctx) {
+		if err := hook(ctx, conn, event, user); err != nil {
+			return err
+		}
+	}
+
 	if config.Webhook.URL != "" {
 		hookURL, err := url.Parse(config.Webhook.URL)
 		if err != nil {
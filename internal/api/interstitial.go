@@ -0,0 +1,52 @@
+package api
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"github.com/supabase/auth/internal/observability"
+)
+
+var interstitialTemplate = template.Must(template.New("interstitial").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="referrer" content="no-referrer">
+<title>Continue to the app</title>
+</head>
+<body>
+<p><a id="continue" href="{{ .URL }}">Continue to the app</a></p>
+<script>window.location.replace(document.getElementById("continue").href);</script>
+</body>
+</html>
+`))
+
+// redirectFromEmailLink redirects to where an email link should take the
+// user. Email clients may block redirects to non-https URLs, such as custom
+// schemes that open a mobile app, so when enabled those are served an
+// interstitial page that links to the URL instead.
+func (a *API) redirectFromEmailLink(w http.ResponseWriter, r *http.Request, rurl string) {
+	if !a.config.Mailer.RedirectInterstitial || isHTTPSURL(rurl) {
+		http.Redirect(w, r, rurl, http.StatusSeeOther)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(http.StatusOK)
+
+	// the URL has already been checked against the redirect allow list
+	if err := interstitialTemplate.Execute(w, map[string]interface{}{
+		"URL": template.URL(rurl), // #nosec G203
+	}); err != nil {
+		observability.GetLogEntry(r).WithError(err).Warn("failed to render redirect interstitial")
+	}
+}
+
+func isHTTPSURL(rurl string) bool {
+	u, err := url.Parse(rurl)
+	return err == nil && u.Scheme == "https"
+}
@@ -3,6 +3,8 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/fatih/structs"
 	"github.com/supabase/auth/internal/api/provider"
@@ -13,8 +15,13 @@ import (
 
 // InviteParams are the parameters the Signup endpoint accepts
 type InviteParams struct {
-	Email string                 `json:"email"`
-	Data  map[string]interface{} `json:"data"`
+	Email       string                 `json:"email"`
+	Data        map[string]interface{} `json:"data"`
+	Role        string                 `json:"role"`
+	AppMetaData map[string]interface{} `json:"app_metadata"`
+	// ExpiryDuration overrides how long the invite link is valid for,
+	// instead of the default email OTP expiry.
+	ExpiryDuration string `json:"expiry_duration"`
 }
 
 // Invite is the endpoint for inviting a new user
@@ -39,6 +46,19 @@ func (a *API) Invite(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
+	var expiresAt *time.Time
+	if params.ExpiryDuration != "" {
+		duration, err := time.ParseDuration(params.ExpiryDuration)
+		if err != nil {
+			return badRequestError("invalid format for expiry duration: %v", err)
+		}
+		if duration <= 0 {
+			return badRequestError("expiry duration must be positive")
+		}
+		t := time.Now().Add(duration)
+		expiresAt = &t
+	}
+
 	aud := a.requestAud(ctx, r)
 	user, err := models.FindUserByEmailAndAudience(db, params.Email, aud)
 	if err != nil && !models.IsNotFoundError(err) {
@@ -80,6 +100,14 @@ func (a *API) Invite(w http.ResponseWriter, r *http.Request) error {
 			user.Identities = []models.Identity{*identity}
 		}
 
+		// the role and app metadata are only applied once the invite is
+		// accepted, in the same transaction that confirms the user
+		user.InviteRole = nil
+		if role := strings.TrimSpace(params.Role); role != "" {
+			user.InviteRole = &role
+		}
+		user.InviteAppMetaData = params.AppMetaData
+
 		if terr := models.NewAuditLogEntry(r, tx, adminUser, models.UserInvitedAction, "", map[string]interface{}{
 			"user_id":    user.ID,
 			"user_email": user.Email,
@@ -90,7 +118,7 @@ func (a *API) Invite(w http.ResponseWriter, r *http.Request) error {
 		mailer := a.Mailer(ctx)
 		referrer := utilities.GetReferrer(r, config)
 		externalURL := getExternalHost(ctx)
-		if err := sendInvite(tx, user, mailer, referrer, externalURL, config.Mailer.OtpLength); err != nil {
+		if err := sendInvite(tx, user, mailer, referrer, externalURL, config.Mailer.OtpLength, expiresAt); err != nil {
 			return internalServerError("Error inviting user").WithInternalError(err)
 		}
 		return nil
@@ -101,3 +129,57 @@ func (a *API) Invite(w http.ResponseWriter, r *http.Request) error {
 
 	return sendJSON(w, http.StatusOK, user)
 }
+
+// adminInvites lists the invites that have not been accepted yet.
+func (a *API) adminInvites(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	aud := a.requestAud(ctx, r)
+
+	pageParams, err := paginate(r)
+	if err != nil {
+		return badRequestError("Bad Pagination Parameters: %v", err)
+	}
+
+	users, err := models.FindPendingInvitesInAudience(db, aud, pageParams)
+	if err != nil {
+		return internalServerError("Database error finding invites").WithInternalError(err)
+	}
+	addPaginationHeaders(w, r, pageParams)
+
+	return sendJSON(w, http.StatusOK, AdminListUsersResponse{
+		Users: users,
+		Aud:   aud,
+	})
+}
+
+// adminInviteRevoke revokes an invite that has not been accepted yet by
+// deleting the invited user.
+func (a *API) adminInviteRevoke(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	user := getUser(ctx)
+	adminUser := getAdminUser(ctx)
+
+	if !user.IsPendingInvite() {
+		return notFoundError("Invite not found")
+	}
+
+	err := a.db.Transaction(func(tx *storage.Connection) error {
+		if terr := models.NewAuditLogEntry(r, tx, adminUser, models.UserInviteRevokedAction, "", map[string]interface{}{
+			"user_id":    user.ID,
+			"user_email": user.Email,
+		}); terr != nil {
+			return internalServerError("Error recording audit log entry").WithInternalError(terr)
+		}
+
+		if terr := tx.Destroy(user); terr != nil {
+			return internalServerError("Database error deleting user").WithInternalError(terr)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusOK, map[string]interface{}{})
+}
@@ -95,6 +95,213 @@ func (ts *InviteTestSuite) TestInvite() {
 	assert.Equal(ts.T(), http.StatusOK, w.Code)
 }
 
+func (ts *InviteTestSuite) TestInviteWithRoleAndAppMetadata() {
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+		"email": "test@example.com",
+		"role":  "editor",
+		"app_metadata": map[string]interface{}{
+			"team": "engineering",
+		},
+		"expiry_duration": "48h",
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/invite", &buffer)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	user, err := models.FindUserByEmailAndAudience(ts.API.db, "test@example.com", ts.Config.JWT.Aud)
+	require.NoError(ts.T(), err)
+	// the role and app metadata are only applied once the invite is accepted
+	assert.NotEqual(ts.T(), "editor", user.Role)
+	assert.NotContains(ts.T(), user.AppMetaData, "team")
+	require.NotNil(ts.T(), user.InviteRole)
+	assert.Equal(ts.T(), "editor", *user.InviteRole)
+	assert.Equal(ts.T(), "engineering", user.InviteAppMetaData["team"])
+	require.NotNil(ts.T(), user.InviteExpiresAt)
+	assert.WithinDuration(ts.T(), time.Now().Add(48*time.Hour), *user.InviteExpiresAt, time.Minute)
+
+	// the invite is listed until it is accepted
+	req = httptest.NewRequest(http.MethodGet, "http://localhost/admin/invites", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+
+	w = httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	data := AdminListUsersResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+	require.Len(ts.T(), data.Users, 1)
+	assert.Equal(ts.T(), user.ID, data.Users[0].ID)
+
+	// revoking the invite deletes the invited user
+	req = httptest.NewRequest(http.MethodDelete, fmt.Sprintf("http://localhost/admin/invites/%s", user.ID), nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+
+	w = httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	_, err = models.FindUserByEmailAndAudience(ts.API.db, "test@example.com", ts.Config.JWT.Aud)
+	assert.True(ts.T(), models.IsNotFoundError(err))
+}
+
+func (ts *InviteTestSuite) TestVerifyInviteAppliesRoleAndAppMetadata() {
+	user, err := models.NewUser("", "test@example.com", "", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err)
+	now := time.Now()
+	role := "editor"
+	user.InvitedAt = &now
+	user.ConfirmationSentAt = &now
+	user.ConfirmationToken = crypto.GenerateTokenHash("test@example.com", "123456")
+	user.InviteRole = &role
+	user.InviteAppMetaData = map[string]interface{}{
+		"team": "engineering",
+	}
+	require.NoError(ts.T(), ts.API.db.Create(user))
+
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+		"email": "test@example.com",
+		"type":  "invite",
+		"token": "123456",
+	}))
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/verify", &buffer)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	// the access token issued for the accepted invite already has the role
+	token := &AccessTokenResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(token))
+	assert.Equal(ts.T(), "editor", token.User.Role)
+
+	user, err = models.FindUserByID(ts.API.db, user.ID)
+	require.NoError(ts.T(), err)
+	assert.True(ts.T(), user.IsConfirmed())
+	assert.Equal(ts.T(), "editor", user.Role)
+	assert.Equal(ts.T(), "engineering", user.AppMetaData["team"])
+	assert.Nil(ts.T(), user.InviteRole)
+	assert.Nil(ts.T(), user.InviteAppMetaData)
+}
+
+func (ts *InviteTestSuite) TestAdminConfirmationSettlesInvite() {
+	createInvitedUser := func(email string, expiresAt *time.Time) *models.User {
+		user, err := models.NewUser("", email, "", ts.Config.JWT.Aud, nil)
+		require.NoError(ts.T(), err)
+		now := time.Now()
+		role := "editor"
+		user.InvitedAt = &now
+		user.ConfirmationSentAt = &now
+		user.InviteExpiresAt = expiresAt
+		user.InviteRole = &role
+		user.InviteAppMetaData = map[string]interface{}{
+			"team": "engineering",
+		}
+		require.NoError(ts.T(), ts.API.db.Create(user))
+		return user
+	}
+	confirm := func(user *models.User, params map[string]interface{}) *models.User {
+		var buffer bytes.Buffer
+		require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(params))
+		req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("http://localhost/admin/users/%s", user.ID), &buffer)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+		w := httptest.NewRecorder()
+		ts.API.handler.ServeHTTP(w, req)
+		require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+		user, err := models.FindUserByID(ts.API.db, user.ID)
+		require.NoError(ts.T(), err)
+		require.True(ts.T(), user.IsConfirmed())
+		assert.Nil(ts.T(), user.InviteRole)
+		assert.Nil(ts.T(), user.InviteAppMetaData)
+		assert.Nil(ts.T(), user.InviteExpiresAt)
+		return user
+	}
+
+	// confirming the email accepts the invite
+	user := confirm(createInvitedUser("accepted@example.com", nil), map[string]interface{}{
+		"email_confirm": true,
+	})
+	assert.Equal(ts.T(), "editor", user.Role)
+	assert.Equal(ts.T(), "engineering", user.AppMetaData["team"])
+
+	// a role set by the admin takes precedence over the invite's
+	user = confirm(createInvitedUser("overridden@example.com", nil), map[string]interface{}{
+		"email_confirm": true,
+		"role":          "viewer",
+	})
+	assert.Equal(ts.T(), "viewer", user.Role)
+	assert.Equal(ts.T(), "engineering", user.AppMetaData["team"])
+
+	// an expired invite is discarded
+	expiredAt := time.Now().Add(-time.Hour)
+	user = confirm(createInvitedUser("expired@example.com", &expiredAt), map[string]interface{}{
+		"email_confirm": true,
+	})
+	assert.NotEqual(ts.T(), "editor", user.Role)
+	assert.NotContains(ts.T(), user.AppMetaData, "team")
+}
+
+func (ts *InviteTestSuite) TestSignupConfirmationClearsInvite() {
+	// To allow us to send the invite and the signup confirmation in succession
+	ts.Config.SMTP.MaxFrequency = 5
+
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+		"email":           "test@example.com",
+		"role":            "editor",
+		"expiry_duration": "1s",
+	}))
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/invite", &buffer)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	buffer.Reset()
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+		"email": "test@example.com",
+		"type":  "signup",
+	}))
+	req = httptest.NewRequest(http.MethodPost, "http://localhost/resend", &buffer)
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	// the signup confirmation gets the default expiry and doesn't carry over
+	// the invite's role
+	user, err := models.FindUserByEmailAndAudience(ts.API.db, "test@example.com", ts.Config.JWT.Aud)
+	require.NoError(ts.T(), err)
+	assert.Nil(ts.T(), user.InviteExpiresAt)
+	assert.Nil(ts.T(), user.InviteRole)
+	assert.Equal(ts.T(), ts.Config.Mailer.OtpExp, confirmationOtpExp(user, ts.Config.Mailer.OtpExp))
+}
+
+func (ts *InviteTestSuite) TestInviteInvalidExpiryDuration() {
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+		"email":           "test@example.com",
+		"expiry_duration": "-1h",
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/invite", &buffer)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.token))
+
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	assert.Equal(ts.T(), http.StatusBadRequest, w.Code)
+}
+
 func (ts *InviteTestSuite) TestInviteAfterSignupShouldNotReturnSensitiveFields() {
 	// To allow us to send signup and invite request in succession
 	ts.Config.SMTP.MaxFrequency = 5
@@ -18,22 +18,27 @@ func (a *API) ListenAndServe(ctx context.Context, hostAndPort string) {
 
 	server := &http.Server{
 		Addr:              hostAndPort,
-		Handler:           a.handler,
+		Handler:           a.Handler(),
 		ReadHeaderTimeout: 2 * time.Second, // to mitigate a Slowloris attack
 		BaseContext: func(net.Listener) context.Context {
 			return baseCtx
 		},
 	}
 
+	shutdownDone := make(chan struct{})
+
 	cleanupWaitGroup.Add(1)
 	go func() {
 		defer cleanupWaitGroup.Done()
+		defer close(shutdownDone)
 
 		<-ctx.Done()
 
-		defer cancel() // close baseContext
+		defer cancel() // close baseContext, canceling requests still in flight
+
+		log.Infof("draining in-flight requests for up to %v", a.config.API.ShutdownTimeout)
 
-		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), time.Minute)
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), a.config.API.ShutdownTimeout)
 		defer shutdownCancel()
 
 		if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, context.Canceled) {
@@ -44,4 +49,8 @@ func (a *API) ListenAndServe(ctx context.Context, hostAndPort string) {
 	if err := server.ListenAndServe(); err != http.ErrServerClosed {
 		log.WithError(err).Fatal("http server listen failed")
 	}
+
+	// ListenAndServe returns as soon as the shutdown starts, wait for the
+	// in-flight requests so the database isn't closed from under them
+	<-shutdownDone
 }
@@ -100,7 +100,7 @@ func (a *API) MagicLink(w http.ResponseWriter, r *http.Request) error {
 		r.ContentLength = int64(len(string(newBodyContent)))
 
 		fakeResponse := &responseStub{}
-		if config.Mailer.Autoconfirm {
+		if config.Mailer.ShouldAutoconfirm(params.Email) {
 			// signups are autoconfirmed, send magic link after signup
 			if err := a.Signup(fakeResponse, r); err != nil {
 				return err
@@ -124,7 +124,11 @@ func (a *API) adminGenerateLink(w http.ResponseWriter, r *http.Request) error {
 			}
 			user.RecoveryToken = hashedToken
 			user.RecoverySentAt = &now
-			terr = errors.Wrap(tx.UpdateOnly(user, "recovery_token", "recovery_sent_at"), "Database error updating user for recovery")
+			user.MagicLinkSentAt = nil
+			if params.Type == magicLinkVerification {
+				user.MagicLinkSentAt = &now
+			}
+			terr = errors.Wrap(tx.UpdateOnly(user, "recovery_token", "recovery_sent_at", "magic_link_sent_at"), "Database error updating user for recovery")
 		case inviteVerification:
 			if user != nil {
 				if user.IsConfirmed() {
@@ -271,10 +275,15 @@ func sendConfirmation(tx *storage.Connection, u *models.User, mailer mailer.Mail
 		return errors.Wrap(err, "Error sending confirmation email")
 	}
 	u.ConfirmationSentAt = &now
-	return errors.Wrap(tx.UpdateOnly(u, "confirmation_token", "confirmation_sent_at"), "Database error updating user for confirmation")
+	// a new confirmation token replaces the invite, along with its expiry,
+	// role and app metadata
+	u.InviteExpiresAt = nil
+	u.InviteRole = nil
+	u.InviteAppMetaData = nil
+	return errors.Wrap(tx.UpdateOnly(u, "confirmation_token", "confirmation_sent_at", "invite_expires_at", "invite_role", "invite_app_metadata"), "Database error updating user for confirmation")
 }
 
-func sendInvite(tx *storage.Connection, u *models.User, mailer mailer.Mailer, referrerURL string, externalURL *url.URL, otpLength int) error {
+func sendInvite(tx *storage.Connection, u *models.User, mailer mailer.Mailer, referrerURL string, externalURL *url.URL, otpLength int, expiresAt *time.Time) error {
 	var err error
 	oldToken := u.ConfirmationToken
 	otp, err := crypto.GenerateOtp(otpLength)
@@ -289,7 +298,8 @@ func sendInvite(tx *storage.Connection, u *models.User, mailer mailer.Mailer, re
 	}
 	u.InvitedAt = &now
 	u.ConfirmationSentAt = &now
-	return errors.Wrap(tx.UpdateOnly(u, "confirmation_token", "confirmation_sent_at", "invited_at"), "Database error updating user for invite")
+	u.InviteExpiresAt = expiresAt
+	return errors.Wrap(tx.UpdateOnly(u, "confirmation_token", "confirmation_sent_at", "invited_at", "invite_expires_at", "invite_role", "invite_app_metadata"), "Database error updating user for invite")
 }
 
 func (a *API) sendPasswordRecovery(tx *storage.Connection, u *models.User, mailer mailer.Mailer, maxFrequency time.Duration, referrerURL string, externalURL *url.URL, otpLength int, flowType models.FlowType) error {
@@ -311,7 +321,8 @@ func (a *API) sendPasswordRecovery(tx *storage.Connection, u *models.User, maile
 		return errors.Wrap(err, "Error sending recovery email")
 	}
 	u.RecoverySentAt = &now
-	return errors.Wrap(tx.UpdateOnly(u, "recovery_token", "recovery_sent_at"), "Database error updating user for recovery")
+	u.MagicLinkSentAt = nil
+	return errors.Wrap(tx.UpdateOnly(u, "recovery_token", "recovery_sent_at", "magic_link_sent_at"), "Database error updating user for recovery")
 }
 
 func (a *API) sendReauthenticationOtp(tx *storage.Connection, u *models.User, mailer mailer.Mailer, maxFrequency time.Duration, otpLength int) error {
@@ -359,7 +370,8 @@ func (a *API) sendMagicLink(tx *storage.Connection, u *models.User, mailer maile
 		return errors.Wrap(err, "Error sending magic link email")
 	}
 	u.RecoverySentAt = &now
-	return errors.Wrap(tx.UpdateOnly(u, "recovery_token", "recovery_sent_at"), "Database error updating user for recovery")
+	u.MagicLinkSentAt = &now
+	return errors.Wrap(tx.UpdateOnly(u, "recovery_token", "recovery_sent_at", "magic_link_sent_at"), "Database error updating user for recovery")
 }
 
 // sendEmailChange sends out an email change token to the new email.
@@ -7,11 +7,13 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/aaronarduino/goqrsvg"
 	svg "github.com/ajstarks/svgo"
 	"github.com/boombuler/barcode/qr"
 	"github.com/gofrs/uuid"
+	"github.com/pquerna/otp"
 	"github.com/pquerna/otp/totp"
 	"github.com/supabase/auth/internal/hooks"
 	"github.com/supabase/auth/internal/metering"
@@ -383,36 +385,8 @@ func (a *API) VerifyFactor(w http.ResponseWriter, r *http.Request) error {
 		return badRequestError("%v has expired, verify against another challenge or create a new challenge.", challenge.ID)
 	}
 
-	valid := totp.Validate(params.Code, factor.Secret)
-
-	if config.Hook.MFAVerificationAttempt.Enabled {
-		input := hooks.MFAVerificationAttemptInput{
-			UserID:   user.ID,
-			FactorID: factor.ID,
-			Valid:    valid,
-		}
-
-		output := hooks.MFAVerificationAttemptOutput{}
-
-		err := a.invokeHook(ctx, &input, &output)
-		if err != nil {
-			return err
-		}
-
-		if output.Decision == hooks.HookRejection {
-			if err := models.Logout(a.db, user.ID); err != nil {
-				return err
-			}
-
-			if output.Message == "" {
-				output.Message = hooks.DefaultMFAHookRejectionMessage
-			}
-
-			return forbiddenError(output.Message)
-		}
-	}
-	if !valid {
-		return badRequestError("Invalid TOTP code entered")
+	if err := a.validateTOTPCode(ctx, user, factor, params.Code); err != nil {
+		return err
 	}
 
 	var token *AccessTokenResponse
@@ -462,6 +436,61 @@ func (a *API) VerifyFactor(w http.ResponseWriter, r *http.Request) error {
 
 }
 
+// validateTOTPCode checks the code against the factor's secret, letting the
+// MFA verification attempt hook reject the attempt.
+func (a *API) validateTOTPCode(ctx context.Context, user *models.User, factor *models.Factor, code string) error {
+	config := a.config
+	valid := totp.Validate(code, factor.Secret)
+
+	if config.Hook.MFAVerificationAttempt.Enabled {
+		input := hooks.MFAVerificationAttemptInput{
+			UserID:   user.ID,
+			FactorID: factor.ID,
+			Valid:    valid,
+		}
+
+		output := hooks.MFAVerificationAttemptOutput{}
+
+		err := a.invokeHook(ctx, &input, &output)
+		if err != nil {
+			return err
+		}
+
+		if output.Decision == hooks.HookRejection {
+			if err := models.Logout(a.db, user.ID); err != nil {
+				return err
+			}
+
+			if output.Message == "" {
+				output.Message = hooks.DefaultMFAHookRejectionMessage
+			}
+
+			return forbiddenError(output.Message)
+		}
+	}
+	if !valid {
+		return badRequestError("Invalid TOTP code entered")
+	}
+	return nil
+}
+
+// totpTimeStep returns the time step of a TOTP code accepted by
+// totp.Validate, which also accepts codes of the previous and next step.
+func totpTimeStep(code, secret string, now time.Time) (uint64, bool) {
+	const period = 30
+	for _, offset := range []int64{0, -1, 1} {
+		t := now.Add(time.Duration(offset*period) * time.Second)
+		if valid, _ := totp.ValidateCustom(code, secret, t, totp.ValidateOpts{
+			Period:    period,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		}); valid {
+			return uint64(t.Unix()) / period, true
+		}
+	}
+	return 0, false
+}
+
 func (a *API) UnenrollFactor(w http.ResponseWriter, r *http.Request) error {
 	var err error
 	ctx := r.Context()
@@ -79,18 +79,14 @@ func (a *API) limitEmailOrPhoneSentHandler() middlewareHandler {
 	emailFreq := a.config.RateLimitEmailSent / (60 * 60)
 	smsFreq := a.config.RateLimitSmsSent / (60 * 60)
 
-	emailLimiter := tollbooth.NewLimiter(emailFreq, &limiter.ExpirableOptions{
-		DefaultExpirationTTL: time.Hour,
-	}).SetBurst(int(a.config.RateLimitEmailSent)).SetMethods([]string{"PUT", "POST"})
-
-	phoneLimiter := tollbooth.NewLimiter(smsFreq, &limiter.ExpirableOptions{
-		DefaultExpirationTTL: time.Hour,
-	}).SetBurst(int(a.config.RateLimitSmsSent)).SetMethods([]string{"PUT", "POST"})
+	emailLimiter := a.rateLimiter("email_sent", emailFreq, int(a.config.RateLimitEmailSent), time.Hour).SetMethods([]string{"PUT", "POST"})
+	phoneLimiter := a.rateLimiter("sms_sent", smsFreq, int(a.config.RateLimitSmsSent), time.Hour).SetMethods([]string{"PUT", "POST"})
 
 	return func(w http.ResponseWriter, req *http.Request) (context.Context, error) {
 		c := req.Context()
 		config := a.config
-		shouldRateLimitEmail := config.External.Email.Enabled && !config.Mailer.Autoconfirm
+		// whether an email is sent depends on the domain, see below
+		shouldRateLimitEmail := config.External.Email.Enabled
 		shouldRateLimitPhone := config.External.Phone.Enabled && !config.Sms.Autoconfirm
 
 		if shouldRateLimitEmail || shouldRateLimitPhone {
@@ -110,7 +106,7 @@ func (a *API) limitEmailOrPhoneSentHandler() middlewareHandler {
 				}
 
 				if shouldRateLimitEmail {
-					if requestBody.Email != "" {
+					if requestBody.Email != "" && !config.Mailer.ShouldAutoconfirm(requestBody.Email) {
 						if err := tollbooth.LimitByKeys(emailLimiter, []string{"email_functions"}); err != nil {
 							emailRateLimitCounter.Add(
 								req.Context(),
@@ -233,6 +229,32 @@ func (a *API) requireSAMLEnabled(w http.ResponseWriter, req *http.Request) (cont
 	return ctx, nil
 }
 
+func (a *API) requireDeviceAuthorizationEnabled(w http.ResponseWriter, req *http.Request) (context.Context, error) {
+	ctx := req.Context()
+	if !a.config.Device.Enabled {
+		return nil, notFoundError("Device authorization is disabled")
+	}
+	return ctx, nil
+}
+
+func (a *API) requireOAuthServerEnabled(w http.ResponseWriter, req *http.Request) (context.Context, error) {
+	ctx := req.Context()
+	if !a.config.OAuthServer.Enabled {
+		return nil, notFoundError("OAuth server is disabled")
+	}
+	return ctx, nil
+}
+
+// requireOAuthClientsEnabled allows registering OAuth clients when they
+// are used by the OAuth server or the device authorization grant.
+func (a *API) requireOAuthClientsEnabled(w http.ResponseWriter, req *http.Request) (context.Context, error) {
+	ctx := req.Context()
+	if !a.config.OAuthServer.Enabled && !a.config.Device.Enabled {
+		return nil, notFoundError("OAuth server is disabled")
+	}
+	return ctx, nil
+}
+
 func (a *API) requireManualLinkingEnabled(w http.ResponseWriter, req *http.Request) (context.Context, error) {
 	ctx := req.Context()
 	if !a.config.Security.ManualLinkingEnabled {
@@ -266,3 +288,11 @@ func (a *API) databaseCleanup(cleanup *models.Cleanup) func(http.Handler) http.H
 		})
 	}
 }
+
+// withStorageHooks makes the storage hooks of the API available to
+// triggerEventHooks.
+func (a *API) withStorageHooks(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(withStorageHooks(r.Context(), a.storageHooks)))
+	})
+}
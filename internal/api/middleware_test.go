@@ -231,6 +231,56 @@ func (ts *MiddlewareTestSuite) TestLimitEmailOrPhoneSentHandler() {
 	}
 }
 
+func (ts *MiddlewareTestSuite) TestLimitEmailSentHandlerWithAutoconfirmDomains() {
+	ts.Config.RateLimitEmailSent = 5
+	ts.Config.Mailer.Autoconfirm = true
+	ts.Config.Mailer.RequireConfirmationDomains = []string{"example.com"}
+	defer func() {
+		ts.Config.Mailer.Autoconfirm = false
+		ts.Config.Mailer.RequireConfirmationDomains = nil
+	}()
+
+	cases := []struct {
+		desc        string
+		email       string
+		rateLimited bool
+	}{
+		{
+			desc:        "Domain requiring confirmation is rate limited",
+			email:       "test@example.com",
+			rateLimited: true,
+		},
+		{
+			desc:        "Autoconfirmed domain is not rate limited",
+			email:       "test@trusted.com",
+			rateLimited: false,
+		},
+	}
+
+	for _, c := range cases {
+		ts.Run(c.desc, func() {
+			limiter := ts.API.limitEmailOrPhoneSentHandler()
+			var err error
+			for i := 0; i < 6; i++ {
+				var buffer bytes.Buffer
+				require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+					"email": c.email,
+				}))
+				req := httptest.NewRequest(http.MethodPost, "http://localhost/signup", &buffer)
+				req.Header.Set("Content-Type", "application/json")
+				_, err = limiter(httptest.NewRecorder(), req)
+			}
+
+			if c.rateLimited {
+				require.Error(ts.T(), err)
+				require.Equal(ts.T(), "429: Email rate limit exceeded", err.Error())
+			} else {
+				require.NoError(ts.T(), err)
+			}
+		})
+	}
+}
+
 func (ts *MiddlewareTestSuite) TestIsValidExternalHost() {
 	cases := []struct {
 		desc        string
@@ -0,0 +1,651 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/gofrs/uuid"
+	jwt "github.com/golang-jwt/jwt"
+	"github.com/supabase/auth/internal/metering"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// oauthServerSupportedScopes lists the scopes third-party clients can request.
+var oauthServerSupportedScopes = map[string]bool{
+	"openid":  true,
+	"email":   true,
+	"phone":   true,
+	"profile": true,
+}
+
+const oauthServerDefaultScope = "openid"
+
+const oauthClientTokenMsg = "Access tokens issued to OAuth clients can only be used with the userinfo endpoint"
+
+// OAuthConsentParams are the parameters the consent endpoint accepts.
+type OAuthConsentParams struct {
+	Deny bool `json:"deny"`
+}
+
+// OAuthAuthorizationDetailsResponse describes a pending authorization request
+// so that the consent page can show what the user is agreeing to.
+type OAuthAuthorizationDetailsResponse struct {
+	AuthorizationID string   `json:"authorization_id"`
+	ClientID        string   `json:"client_id"`
+	ClientName      string   `json:"client_name"`
+	RedirectURI     string   `json:"redirect_uri"`
+	Scopes          []string `json:"scopes"`
+}
+
+// OAuthConsentResponse holds the client redirect URL the consent page should
+// navigate to once the user has made a decision.
+type OAuthConsentResponse struct {
+	RedirectURL string `json:"redirect_url"`
+}
+
+// OAuthTokenParams are the parameters the OAuthAuthorizationCodeGrant and
+// ClientCredentialsGrant methods accept
+type OAuthTokenParams struct {
+	Code         string `json:"code"`
+	RedirectURI  string `json:"redirect_uri"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// UserInfoResponse holds the OpenID Connect standard claims about the user,
+// see OpenID Connect Core 1.0 Section 5.1. Only the claims of the scopes
+// granted to the client are set.
+type UserInfoResponse struct {
+	Subject             string `json:"sub"`
+	Email               string `json:"email,omitempty"`
+	EmailVerified       *bool  `json:"email_verified,omitempty"`
+	PhoneNumber         string `json:"phone_number,omitempty"`
+	PhoneNumberVerified *bool  `json:"phone_number_verified,omitempty"`
+	Name                string `json:"name,omitempty"`
+	Picture             string `json:"picture,omitempty"`
+	UpdatedAt           int64  `json:"updated_at,omitempty"`
+}
+
+// IDTokenClaims are the claims of the ID token issued to clients that
+// requested the openid scope, see OpenID Connect Core 1.0 Section 2. The
+// azp claim marks it as an ID token, which is never accepted as an access
+// token.
+type IDTokenClaims struct {
+	jwt.StandardClaims
+	AuthorizedParty     string `json:"azp"`
+	AuthTime            int64  `json:"auth_time,omitempty"`
+	Nonce               string `json:"nonce,omitempty"`
+	Email               string `json:"email,omitempty"`
+	EmailVerified       *bool  `json:"email_verified,omitempty"`
+	PhoneNumber         string `json:"phone_number,omitempty"`
+	PhoneNumberVerified *bool  `json:"phone_number_verified,omitempty"`
+}
+
+// OpenIDConfigurationResponse is the OpenID Provider metadata, see OpenID
+// Connect Discovery 1.0 Section 3. Tokens are signed with the symmetric JWT
+// secret, so there are no public keys to publish with a jwks_uri; clients
+// rely on receiving the ID token directly from the token endpoint over TLS.
+type OpenIDConfigurationResponse struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// OAuthServerAuthorize validates an authorization request from a registered
+// client (RFC 6749 Section 4.1.1) and sends the user to the consent page.
+func (a *API) OAuthServerAuthorize(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	config := a.config
+	query := r.URL.Query()
+
+	clientID, err := uuid.FromString(query.Get("client_id"))
+	if err != nil {
+		return badRequestError("Invalid client_id")
+	}
+
+	client, err := models.FindOAuthClientByID(db, clientID)
+	if err != nil {
+		if models.IsNotFoundError(err) {
+			return badRequestError("Invalid client_id")
+		}
+		return internalServerError("Database error finding OAuth client").WithInternalError(err)
+	}
+
+	// errors about the client or redirect URI must not be redirected, as
+	// the redirect URI can't be trusted
+	redirectURI := query.Get("redirect_uri")
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+	if !client.HasRedirectURI(redirectURI) {
+		return badRequestError("redirect_uri is not registered for this client")
+	}
+
+	state := query.Get("state")
+
+	if query.Get("response_type") != "code" {
+		return redirectOAuthError(w, r, redirectURI, state, "unsupported_response_type", "Only the code response type is supported")
+	}
+
+	scope, err := parseOAuthServerScope(query.Get("scope"))
+	if err != nil {
+		return redirectOAuthError(w, r, redirectURI, state, "invalid_scope", err.Error())
+	}
+
+	codeChallenge := query.Get("code_challenge")
+	codeChallengeMethod := query.Get("code_challenge_method")
+	if codeChallenge == "" && !client.IsConfidential() {
+		return redirectOAuthError(w, r, redirectURI, state, "invalid_request", "PKCE is required for public clients")
+	}
+	if codeChallenge != "" {
+		if codeChallengeMethod == "" {
+			codeChallengeMethod = models.Plain.String()
+		}
+		method, err := models.ParseCodeChallengeMethod(codeChallengeMethod)
+		if err != nil {
+			return redirectOAuthError(w, r, redirectURI, state, "invalid_request", err.Error())
+		}
+		codeChallengeMethod = method.String()
+	}
+
+	authorization := models.NewOAuthAuthorization(client, redirectURI, scope, state, query.Get("nonce"), codeChallenge, codeChallengeMethod)
+	if err := db.Create(authorization); err != nil {
+		return internalServerError("Database error creating OAuth authorization").WithInternalError(err)
+	}
+
+	consentURL, err := url.Parse(config.OAuthServer.ConsentURL)
+	if err != nil {
+		return internalServerError("Invalid OAuth consent URL").WithInternalError(err)
+	}
+	q := consentURL.Query()
+	q.Set("authorization_id", authorization.ID.String())
+	consentURL.RawQuery = q.Encode()
+
+	http.Redirect(w, r, consentURL.String(), http.StatusFound)
+	return nil
+}
+
+// OAuthServerGetAuthorization returns the details of a pending authorization
+// request to the consent page.
+func (a *API) OAuthServerGetAuthorization(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	authorization, client, err := a.findPendingOAuthAuthorization(db, chi.URLParam(r, "authorization_id"))
+	if err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusOK, &OAuthAuthorizationDetailsResponse{
+		AuthorizationID: authorization.ID.String(),
+		ClientID:        client.ID.String(),
+		ClientName:      client.Name,
+		RedirectURI:     authorization.RedirectURI,
+		Scopes:          strings.Fields(authorization.Scope),
+	})
+}
+
+// OAuthServerConsent lets the signed in user approve or deny a pending
+// authorization request, returning the URL to send the user back to the
+// client with.
+func (a *API) OAuthServerConsent(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+	user := getUser(ctx)
+
+	params := &OAuthConsentParams{}
+	body, err := getBodyBytes(r)
+	if err != nil {
+		return badRequestError("Could not read body").WithInternalError(err)
+	}
+
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, params); err != nil {
+			return badRequestError("Could not read consent params: %v", err)
+		}
+	}
+
+	var redirectURL string
+	err = db.Transaction(func(tx *storage.Connection) error {
+		authorization, client, terr := a.findPendingOAuthAuthorization(tx, chi.URLParam(r, "authorization_id"))
+		if terr != nil {
+			return terr
+		}
+
+		auditPayload := map[string]interface{}{
+			"client_id":   client.ID.String(),
+			"client_name": client.Name,
+		}
+
+		q := url.Values{}
+		if authorization.State != nil {
+			q.Set("state", *authorization.State)
+		}
+
+		if params.Deny {
+			if terr := models.NewAuditLogEntry(r, tx, user, models.OAuthClientDeniedAction, "", auditPayload); terr != nil {
+				return terr
+			}
+			if terr := tx.Destroy(authorization); terr != nil {
+				return internalServerError("Database error removing OAuth authorization").WithInternalError(terr)
+			}
+			q.Set("error", "access_denied")
+			q.Set("error_description", "User denied the authorization request")
+			redirectURL = appendQuery(authorization.RedirectURI, q)
+			return nil
+		}
+
+		if terr := models.NewAuditLogEntry(r, tx, user, models.OAuthClientAuthorizedAction, "", auditPayload); terr != nil {
+			return terr
+		}
+		// the client learns when the user actually signed in through the
+		// auth_time of the ID token
+		authTime := user.LastSignInAt
+		if session := getSession(ctx); session != nil {
+			authTime = &session.CreatedAt
+		}
+		code, terr := authorization.Approve(tx, user.ID, authTime)
+		if terr != nil {
+			return internalServerError("Database error updating OAuth authorization").WithInternalError(terr)
+		}
+		q.Set("code", code)
+		redirectURL = appendQuery(authorization.RedirectURI, q)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return sendJSON(w, http.StatusOK, &OAuthConsentResponse{
+		RedirectURL: redirectURL,
+	})
+}
+
+// OAuthAuthorizationCodeGrant exchanges an authorization code issued to a
+// registered client for an access and refresh token (RFC 6749 Section 4.1.3).
+func (a *API) OAuthAuthorizationCodeGrant(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	db := a.db.WithContext(ctx)
+	config := a.config
+
+	if !config.OAuthServer.Enabled {
+		return oauthError("unsupported_grant_type", "OAuth server is disabled")
+	}
+
+	params, err := getOAuthTokenParams(r)
+	if err != nil {
+		return err
+	}
+
+	if params.Code == "" || params.ClientID == "" {
+		return oauthError("invalid_request", "code and client_id are required")
+	}
+
+	client, err := a.authenticateOAuthClient(db, params.ClientID, params.ClientSecret)
+	if err != nil {
+		return err
+	}
+
+	authorization, err := models.FindOAuthAuthorizationByAuthCode(db, params.Code)
+	if err != nil {
+		if models.IsNotFoundError(err) {
+			return oauthError("invalid_grant", "Invalid authorization code")
+		}
+		return internalServerError("Database error finding OAuth authorization").WithInternalError(err)
+	}
+
+	if authorization.ClientID != client.ID || authorization.UserID == nil {
+		return oauthError("invalid_grant", "Invalid authorization code")
+	}
+	if authorization.IsExpired(config.OAuthServer.AuthorizationCodeExpiryDuration) {
+		return oauthError("invalid_grant", "Authorization code has expired")
+	}
+	if params.RedirectURI != authorization.RedirectURI {
+		return oauthError("invalid_grant", "redirect_uri does not match the authorization request")
+	}
+	if err := authorization.VerifyPKCE(params.CodeVerifier); err != nil {
+		return oauthError("invalid_grant", err.Error())
+	}
+
+	user, err := models.FindUserByID(db, *authorization.UserID)
+	if err != nil {
+		if models.IsNotFoundError(err) {
+			return oauthError("invalid_grant", "Invalid authorization code")
+		}
+		return internalServerError("Database error finding user").WithInternalError(err)
+	}
+
+	if user.IsBanned() {
+		return oauthError("invalid_grant", "Invalid authorization code")
+	}
+
+	// the session is bound to the client, so that the access tokens issued
+	// for it, also when refreshed, are limited to the granted scopes
+	grantParams := models.GrantParams{
+		OAuthClientID: &client.ID,
+		OAuthScope:    &authorization.Scope,
+	}
+	grantParams.FillGrantParams(r)
+
+	var token *AccessTokenResponse
+	err = db.Transaction(func(tx *storage.Connection) error {
+		var terr error
+		// authorization codes are single-use, so remove it before the
+		// token is handed out. A concurrent request that redeemed it first
+		// makes this fail.
+		if terr = authorization.Redeem(tx); terr != nil {
+			if models.IsNotFoundError(terr) {
+				return oauthError("invalid_grant", "Invalid authorization code")
+			}
+			return internalServerError("Database error removing OAuth authorization").WithInternalError(terr)
+		}
+		if terr = models.NewAuditLogEntry(r, tx, user, models.LoginAction, "", map[string]interface{}{
+			"provider":  "oauth_client",
+			"client_id": client.ID.String(),
+		}); terr != nil {
+			return terr
+		}
+		if terr = triggerEventHooks(ctx, tx, LoginEvent, user, config); terr != nil {
+			return terr
+		}
+		token, terr = a.issueRefreshToken(ctx, tx, user, models.OAuthAuthorizationCode, grantParams)
+		return terr
+	})
+	if err != nil {
+		return err
+	}
+
+	// the client only gets the claims of the granted scopes, not the user
+	token.User = nil
+	token.ProfileIncomplete = false
+	if authorization.HasScope("openid") {
+		token.IDToken, err = a.generateIDToken(user, client, authorization)
+		if err != nil {
+			return internalServerError("error generating id token").WithInternalError(err)
+		}
+	}
+
+	metering.RecordLogin("oauth_authorization_code", user.ID)
+	return sendJSON(w, http.StatusOK, token)
+}
+
+// UserInfo implements the OpenID Connect UserInfo endpoint for the user of
+// the access token.
+func (a *API) UserInfo(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	user := getUser(ctx)
+
+	// first-party access tokens are not limited to any scopes
+	scopes := oauthServerSupportedScopes
+	if claims := getClaims(ctx); claims != nil && claims.ClientID != "" {
+		scopes = make(map[string]bool)
+		for _, scope := range strings.Fields(claims.Scope) {
+			scopes[scope] = true
+		}
+	}
+
+	return sendJSON(w, http.StatusOK, newUserInfoResponse(user, scopes))
+}
+
+// OpenIDConfiguration returns the OpenID Provider metadata for third-party
+// OAuth clients.
+func (a *API) OpenIDConfiguration(w http.ResponseWriter, r *http.Request) error {
+	config := a.config
+	baseURL := strings.TrimSuffix(config.API.ExternalURL, "/")
+
+	return sendJSON(w, http.StatusOK, &OpenIDConfigurationResponse{
+		Issuer:                            a.oauthServerIssuer(),
+		AuthorizationEndpoint:             baseURL + "/oauth/authorize",
+		TokenEndpoint:                     baseURL + "/token",
+		UserInfoEndpoint:                  baseURL + "/userinfo",
+		ScopesSupported:                   []string{"openid", "email", "phone", "profile"},
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code", "refresh_token", "client_credentials"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{jwt.SigningMethodHS256.Name},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256", "plain"},
+		ClaimsSupported:                   []string{"sub", "email", "email_verified", "phone_number", "phone_number_verified", "name", "picture", "updated_at"},
+	})
+}
+
+// newUserInfoResponse returns the claims about the user that belong to the
+// given scopes, see OpenID Connect Core 1.0 Section 5.4.
+func newUserInfoResponse(user *models.User, scopes map[string]bool) *UserInfoResponse {
+	resp := &UserInfoResponse{
+		Subject: user.ID.String(),
+	}
+
+	if scopes["email"] {
+		emailVerified := user.IsConfirmed()
+		resp.Email = user.GetEmail()
+		resp.EmailVerified = &emailVerified
+	}
+
+	if scopes["phone"] {
+		phoneVerified := user.IsPhoneConfirmed()
+		resp.PhoneNumber = user.GetPhone()
+		resp.PhoneNumberVerified = &phoneVerified
+	}
+
+	if scopes["profile"] {
+		for _, key := range []string{"name", "full_name"} {
+			if name, ok := user.UserMetaData[key].(string); ok && resp.Name == "" {
+				resp.Name = name
+			}
+		}
+		for _, key := range []string{"picture", "avatar_url"} {
+			if picture, ok := user.UserMetaData[key].(string); ok && resp.Picture == "" {
+				resp.Picture = picture
+			}
+		}
+		resp.UpdatedAt = user.UpdatedAt.Unix()
+	}
+
+	return resp
+}
+
+// oauthServerIssuer is the issuer of ID tokens advertised in the OpenID
+// Provider metadata. It defaults to the external URL of the API.
+func (a *API) oauthServerIssuer() string {
+	if a.config.JWT.Issuer != "" {
+		return a.config.JWT.Issuer
+	}
+	return strings.TrimSuffix(a.config.API.ExternalURL, "/")
+}
+
+// generateIDToken issues the ID token for an authorization that requested
+// the openid scope. It is signed like access tokens, as it is returned
+// directly from the token endpoint to the client.
+func (a *API) generateIDToken(user *models.User, client *models.OAuthClient, authorization *models.OAuthAuthorization) (string, error) {
+	config := a.config
+
+	scopes := make(map[string]bool)
+	for _, scope := range strings.Fields(authorization.Scope) {
+		scopes[scope] = true
+	}
+	userInfo := newUserInfoResponse(user, scopes)
+
+	issuedAt := time.Now().UTC()
+	claims := &IDTokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Subject:   user.ID.String(),
+			Audience:  client.ID.String(),
+			IssuedAt:  issuedAt.Unix(),
+			ExpiresAt: issuedAt.Add(time.Second * time.Duration(config.JWT.Exp)).Unix(),
+			Issuer:    a.oauthServerIssuer(),
+		},
+		AuthorizedParty:     client.ID.String(),
+		Email:               userInfo.Email,
+		EmailVerified:       userInfo.EmailVerified,
+		PhoneNumber:         userInfo.PhoneNumber,
+		PhoneNumberVerified: userInfo.PhoneNumberVerified,
+	}
+	if authorization.Nonce != nil {
+		claims.Nonce = *authorization.Nonce
+	}
+	if authorization.AuthTime != nil {
+		claims.AuthTime = authorization.AuthTime.Unix()
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	if config.JWT.KeyID != "" {
+		token.Header["kid"] = config.JWT.KeyID
+	}
+
+	return token.SignedString([]byte(config.JWT.Secret))
+}
+
+func (a *API) findPendingOAuthAuthorization(tx *storage.Connection, authorizationID string) (*models.OAuthAuthorization, *models.OAuthClient, error) {
+	id, err := uuid.FromString(authorizationID)
+	if err != nil {
+		return nil, nil, notFoundError("OAuth authorization not found")
+	}
+
+	authorization, err := models.FindOAuthAuthorizationByID(tx, id)
+	if err != nil {
+		if models.IsNotFoundError(err) {
+			return nil, nil, notFoundError("OAuth authorization not found")
+		}
+		return nil, nil, internalServerError("Database error finding OAuth authorization").WithInternalError(err)
+	}
+
+	if !authorization.IsPending() || authorization.IsExpired(a.config.OAuthServer.AuthorizationCodeExpiryDuration) {
+		return nil, nil, expiredTokenError("OAuth authorization is invalid or has expired")
+	}
+
+	client, err := models.FindOAuthClientByID(tx, authorization.ClientID)
+	if err != nil {
+		if models.IsNotFoundError(err) {
+			return nil, nil, notFoundError("OAuth client not found")
+		}
+		return nil, nil, internalServerError("Database error finding OAuth client").WithInternalError(err)
+	}
+
+	return authorization, client, nil
+}
+
+// authenticateOAuthClient looks up the client making a token request.
+// Confidential clients must present their client secret.
+func (a *API) authenticateOAuthClient(tx *storage.Connection, clientID, clientSecret string) (*models.OAuthClient, error) {
+	id, err := uuid.FromString(clientID)
+	if err != nil {
+		return nil, oauthError("invalid_client", "Invalid client credentials")
+	}
+
+	client, err := models.FindOAuthClientByID(tx, id)
+	if err != nil {
+		if models.IsNotFoundError(err) {
+			return nil, oauthError("invalid_client", "Invalid client credentials")
+		}
+		return nil, internalServerError("Database error finding OAuth client").WithInternalError(err)
+	}
+
+	if client.IsConfidential() && !client.VerifyClientSecret(clientSecret) {
+		return nil, oauthError("invalid_client", "Invalid client credentials")
+	}
+
+	return client, nil
+}
+
+// getOAuthTokenParams reads the grant parameters from either
+// a JSON or a form encoded body, as most OAuth client libraries use the
+// latter. Client credentials may also be sent with HTTP Basic authentication.
+func getOAuthTokenParams(r *http.Request) (*OAuthTokenParams, error) {
+	params := &OAuthTokenParams{}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		if err := r.ParseForm(); err != nil {
+			return nil, badRequestError("Could not read token params: %v", err)
+		}
+		params.Code = r.PostFormValue("code")
+		params.RedirectURI = r.PostFormValue("redirect_uri")
+		params.ClientID = r.PostFormValue("client_id")
+		params.ClientSecret = r.PostFormValue("client_secret")
+		params.CodeVerifier = r.PostFormValue("code_verifier")
+	} else {
+		body, err := getBodyBytes(r)
+		if err != nil {
+			return nil, badRequestError("Could not read body").WithInternalError(err)
+		}
+		if err := json.Unmarshal(body, params); err != nil {
+			return nil, badRequestError("Could not read token params: %v", err)
+		}
+	}
+
+	if clientID, clientSecret, ok := r.BasicAuth(); ok {
+		params.ClientID = clientID
+		params.ClientSecret = clientSecret
+	}
+
+	return params, nil
+}
+
+// parseOAuthServerScope checks that only supported scopes are requested and
+// returns them in a normalized form.
+func parseOAuthServerScope(scope string) (string, error) {
+	scopes := strings.Fields(scope)
+	if len(scopes) == 0 {
+		return oauthServerDefaultScope, nil
+	}
+
+	seen := make(map[string]bool)
+	normalized := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		if !oauthServerSupportedScopes[s] {
+			return "", fmt.Errorf("unsupported scope %q", s)
+		}
+		if !seen[s] {
+			seen[s] = true
+			normalized = append(normalized, s)
+		}
+	}
+
+	return strings.Join(normalized, " "), nil
+}
+
+// redirectOAuthError sends an error response back to the client's redirect
+// URI, see RFC 6749 Section 4.1.2.1.
+func redirectOAuthError(w http.ResponseWriter, r *http.Request, redirectURI, state, errorCode, description string) error {
+	q := url.Values{}
+	q.Set("error", errorCode)
+	q.Set("error_description", description)
+	if state != "" {
+		q.Set("state", state)
+	}
+
+	http.Redirect(w, r, appendQuery(redirectURI, q), http.StatusFound)
+	return nil
+}
+
+func appendQuery(rawURL string, params url.Values) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	q := u.Query()
+	for key, values := range params {
+		for _, value := range values {
+			q.Add(key, value)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
@@ -0,0 +1,552 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
+)
+
+const oauthTestRedirectURI = "https://client.example.com/callback"
+
+type OAuthServerTestSuite struct {
+	suite.Suite
+	API    *API
+	Config *conf.GlobalConfiguration
+
+	User       *models.User
+	adminToken string
+}
+
+func TestOAuthServer(t *testing.T) {
+	api, config, err := setupAPIForTestWithCallback(func(config *conf.GlobalConfiguration, conn *storage.Connection) {
+		if config != nil {
+			config.OAuthServer.Enabled = true
+		}
+	})
+	require.NoError(t, err)
+
+	ts := &OAuthServerTestSuite{
+		API:    api,
+		Config: config,
+	}
+	defer api.db.Close()
+
+	suite.Run(t, ts)
+}
+
+func (ts *OAuthServerTestSuite) SetupTest() {
+	models.TruncateAll(ts.API.db)
+
+	u, err := models.NewUser("", "test@example.com", "password", ts.Config.JWT.Aud, map[string]interface{}{
+		"full_name": "Test User",
+	})
+	require.NoError(ts.T(), err, "Error creating test user model")
+	require.NoError(ts.T(), ts.API.db.Create(u), "Error saving new test user")
+	ts.User = u
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &AccessTokenClaims{
+		Role: "supabase_admin",
+	}).SignedString([]byte(ts.Config.JWT.Secret))
+	require.NoError(ts.T(), err, "Error generating admin jwt")
+	ts.adminToken = token
+}
+
+func (ts *OAuthServerTestSuite) createClient(clientType string) OAuthClientResponse {
+	return ts.createClientWithParams(CreateOAuthClientParams{
+		Name:         "Example App",
+		ClientType:   clientType,
+		RedirectURIs: []string{oauthTestRedirectURI},
+	})
+}
+
+func (ts *OAuthServerTestSuite) createClientWithParams(params CreateOAuthClientParams) OAuthClientResponse {
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(params))
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/admin/oauth/clients", &buffer)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ts.adminToken))
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusCreated, w.Code)
+
+	var resp OAuthClientResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&resp))
+	return resp
+}
+
+func (ts *OAuthServerTestSuite) authorize(query url.Values) *url.URL {
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/oauth/authorize?"+query.Encode(), nil)
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusFound, w.Code)
+
+	location, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(ts.T(), err)
+	return location
+}
+
+func (ts *OAuthServerTestSuite) userRequest(method, path string, body interface{}) *httptest.ResponseRecorder {
+	token, _, err := ts.API.generateAccessToken(context.Background(), ts.API.db, ts.User, nil, models.PasswordGrant)
+	require.NoError(ts.T(), err)
+
+	var buffer bytes.Buffer
+	if body != nil {
+		require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(body))
+	}
+	req := httptest.NewRequest(method, "http://localhost"+path, &buffer)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (ts *OAuthServerTestSuite) consent(authorizationID string, deny bool) *url.URL {
+	w := ts.userRequest(http.MethodPost, "/oauth/authorizations/"+authorizationID+"/consent", OAuthConsentParams{Deny: deny})
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	var resp OAuthConsentResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&resp))
+	redirectURL, err := url.Parse(resp.RedirectURL)
+	require.NoError(ts.T(), err)
+	return redirectURL
+}
+
+func (ts *OAuthServerTestSuite) exchangeCode(form url.Values) *httptest.ResponseRecorder {
+	form.Set("grant_type", "authorization_code")
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	return w
+}
+
+func (ts *OAuthServerTestSuite) TestAuthorizationCodeFlowWithPKCE() {
+	client := ts.createClient("public")
+	require.Empty(ts.T(), client.ClientSecret)
+
+	codeVerifier := "some-long-and-random-code-verifier-for-testing-purposes"
+	hashed := sha256.Sum256([]byte(codeVerifier))
+	codeChallenge := base64.RawURLEncoding.EncodeToString(hashed[:])
+
+	location := ts.authorize(url.Values{
+		"response_type":         {"code"},
+		"client_id":             {client.ID.String()},
+		"redirect_uri":          {oauthTestRedirectURI},
+		"scope":                 {"openid email"},
+		"state":                 {"xyz"},
+		"nonce":                 {"n-0S6_WzA2Mj"},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	})
+	require.True(ts.T(), strings.HasPrefix(location.String(), ts.Config.OAuthServer.ConsentURL))
+	authorizationID := location.Query().Get("authorization_id")
+	require.NotEmpty(ts.T(), authorizationID)
+
+	w := ts.userRequest(http.MethodGet, "/oauth/authorizations/"+authorizationID, nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+	var details OAuthAuthorizationDetailsResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&details))
+	require.Equal(ts.T(), "Example App", details.ClientName)
+	require.Equal(ts.T(), []string{"openid", "email"}, details.Scopes)
+
+	signedInAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	ts.User.LastSignInAt = &signedInAt
+	require.NoError(ts.T(), ts.User.UpdateLastSignInAt(ts.API.db))
+	redirectURL := ts.consent(authorizationID, false)
+	require.Equal(ts.T(), "xyz", redirectURL.Query().Get("state"))
+	code := redirectURL.Query().Get("code")
+	require.NotEmpty(ts.T(), code)
+
+	// only the hash of the authorization code is stored
+	_, err := models.FindOAuthAuthorizationByAuthCode(ts.API.db, code)
+	require.NoError(ts.T(), err)
+	count, err := ts.API.db.Q().Where("auth_code_hash = ?", code).Count(&models.OAuthAuthorization{})
+	require.NoError(ts.T(), err)
+	require.Zero(ts.T(), count)
+
+	// a wrong code verifier is rejected
+	w = ts.exchangeCode(url.Values{
+		"code":          {code},
+		"client_id":     {client.ID.String()},
+		"redirect_uri":  {oauthTestRedirectURI},
+		"code_verifier": {"wrong-verifier"},
+	})
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+
+	w = ts.exchangeCode(url.Values{
+		"code":          {code},
+		"client_id":     {client.ID.String()},
+		"redirect_uri":  {oauthTestRedirectURI},
+		"code_verifier": {codeVerifier},
+	})
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+	token := &AccessTokenResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(token))
+	require.NotEmpty(ts.T(), token.Token)
+	require.NotEmpty(ts.T(), token.RefreshToken)
+	require.Nil(ts.T(), token.User)
+
+	// the access token is issued for the client and limited to the
+	// granted scopes
+	claims := ts.parseToken(token.Token)
+	require.Equal(ts.T(), client.ID.String(), claims["aud"])
+	require.Equal(ts.T(), client.ID.String(), claims["client_id"])
+	require.Equal(ts.T(), "openid email", claims["scope"])
+
+	idTokenClaims := ts.parseToken(token.IDToken)
+	require.Equal(ts.T(), ts.User.ID.String(), idTokenClaims["sub"])
+	require.Equal(ts.T(), client.ID.String(), idTokenClaims["aud"])
+	require.Equal(ts.T(), client.ID.String(), idTokenClaims["azp"])
+	require.Equal(ts.T(), ts.Config.API.ExternalURL, idTokenClaims["iss"])
+	require.Equal(ts.T(), float64(signedInAt.Unix()), idTokenClaims["auth_time"])
+	require.Equal(ts.T(), "n-0S6_WzA2Mj", idTokenClaims["nonce"])
+	require.Equal(ts.T(), "test@example.com", idTokenClaims["email"])
+	require.NotContains(ts.T(), idTokenClaims, "phone_number")
+
+	// authorization codes are single-use
+	w = ts.exchangeCode(url.Values{
+		"code":          {code},
+		"client_id":     {client.ID.String()},
+		"redirect_uri":  {oauthTestRedirectURI},
+		"code_verifier": {codeVerifier},
+	})
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/userinfo", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token.Token))
+	w = httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+	var userInfo UserInfoResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&userInfo))
+	require.Equal(ts.T(), ts.User.ID.String(), userInfo.Subject)
+	require.Equal(ts.T(), "test@example.com", userInfo.Email)
+	require.NotNil(ts.T(), userInfo.EmailVerified)
+	// the profile scope was not granted
+	require.Empty(ts.T(), userInfo.Name)
+	require.Nil(ts.T(), userInfo.PhoneNumberVerified)
+
+	// the access token can't be used with first-party endpoints
+	for _, path := range []string{"/user", "/factors", "/admin/users"} {
+		req = httptest.NewRequest(http.MethodGet, "http://localhost"+path, nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token.Token))
+		w = httptest.NewRecorder()
+		ts.API.handler.ServeHTTP(w, req)
+		require.Equal(ts.T(), http.StatusForbidden, w.Code, path)
+	}
+
+	// the ID token can't be used as an access token
+	for _, path := range []string{"/user", "/userinfo"} {
+		req = httptest.NewRequest(http.MethodGet, "http://localhost"+path, nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token.IDToken))
+		w = httptest.NewRecorder()
+		ts.API.handler.ServeHTTP(w, req)
+		require.Equal(ts.T(), http.StatusUnauthorized, w.Code, path)
+	}
+	req = httptest.NewRequest(http.MethodPut, "http://localhost/user", strings.NewReader(`{"password":"new-password"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token.IDToken))
+	w = httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusUnauthorized, w.Code)
+	user, err := models.FindUserByID(ts.API.db, ts.User.ID)
+	require.NoError(ts.T(), err)
+	require.True(ts.T(), user.Authenticate(context.Background(), "password"))
+
+	// refreshed access tokens keep the granted scopes
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(RefreshTokenGrantParams{
+		RefreshToken: token.RefreshToken,
+	}))
+	req = httptest.NewRequest(http.MethodPost, "http://localhost/token?grant_type=refresh_token", &buffer)
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+	refreshed := &AccessTokenResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(refreshed))
+	require.Nil(ts.T(), refreshed.User)
+	claims = ts.parseToken(refreshed.Token)
+	require.Equal(ts.T(), client.ID.String(), claims["aud"])
+	require.Equal(ts.T(), "openid email", claims["scope"])
+}
+
+func (ts *OAuthServerTestSuite) TestUserInfoWithFirstPartyToken() {
+	w := ts.userRequest(http.MethodGet, "/userinfo", nil)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	// first-party access tokens are not limited to any scopes
+	var userInfo UserInfoResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&userInfo))
+	require.Equal(ts.T(), "test@example.com", userInfo.Email)
+	require.Equal(ts.T(), "Test User", userInfo.Name)
+}
+
+func (ts *OAuthServerTestSuite) TestOpenIDConfiguration() {
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/.well-known/openid-configuration", nil)
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	var resp OpenIDConfigurationResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&resp))
+	baseURL := strings.TrimSuffix(ts.Config.API.ExternalURL, "/")
+	require.Equal(ts.T(), baseURL, resp.Issuer)
+	require.Equal(ts.T(), baseURL+"/oauth/authorize", resp.AuthorizationEndpoint)
+	require.Equal(ts.T(), baseURL+"/token", resp.TokenEndpoint)
+	require.Equal(ts.T(), baseURL+"/userinfo", resp.UserInfoEndpoint)
+	require.Contains(ts.T(), resp.ScopesSupported, "openid")
+}
+
+func (ts *OAuthServerTestSuite) parseToken(token string) jwt.MapClaims {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(ts.Config.JWT.Secret), nil
+	})
+	require.NoError(ts.T(), err)
+	return claims
+}
+
+func (ts *OAuthServerTestSuite) TestConfidentialClientRequiresSecret() {
+	client := ts.createClient("confidential")
+	require.NotEmpty(ts.T(), client.ClientSecret)
+
+	location := ts.authorize(url.Values{
+		"response_type": {"code"},
+		"client_id":     {client.ID.String()},
+		"redirect_uri":  {oauthTestRedirectURI},
+	})
+	redirectURL := ts.consent(location.Query().Get("authorization_id"), false)
+	code := redirectURL.Query().Get("code")
+
+	w := ts.exchangeCode(url.Values{
+		"code":          {code},
+		"client_id":     {client.ID.String()},
+		"client_secret": {"wrong-secret"},
+		"redirect_uri":  {oauthTestRedirectURI},
+	})
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+	data := &OAuthError{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(data))
+	require.Equal(ts.T(), "invalid_client", data.Err)
+
+	w = ts.exchangeCode(url.Values{
+		"code":          {code},
+		"client_id":     {client.ID.String()},
+		"client_secret": {client.ClientSecret},
+		"redirect_uri":  {oauthTestRedirectURI},
+	})
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+}
+
+func (ts *OAuthServerTestSuite) TestAuthorizationCodeExpiresAfterApproval() {
+	client := ts.createClient("confidential")
+
+	location := ts.authorize(url.Values{
+		"response_type": {"code"},
+		"client_id":     {client.ID.String()},
+		"redirect_uri":  {oauthTestRedirectURI},
+	})
+	authorizationID := location.Query().Get("authorization_id")
+
+	// the user took most of the expiry duration on the consent page
+	createdAt := time.Now().Add(time.Second - ts.Config.OAuthServer.AuthorizationCodeExpiryDuration)
+	require.NoError(ts.T(), ts.API.db.RawQuery("UPDATE oauth_authorizations SET created_at = ? WHERE id = ?", createdAt, authorizationID).Exec())
+	redirectURL := ts.consent(authorizationID, false)
+	time.Sleep(time.Second)
+
+	// the code is valid for the full expiry duration after approval
+	w := ts.exchangeCode(url.Values{
+		"code":          {redirectURL.Query().Get("code")},
+		"client_id":     {client.ID.String()},
+		"client_secret": {client.ClientSecret},
+		"redirect_uri":  {oauthTestRedirectURI},
+	})
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+}
+
+func (ts *OAuthServerTestSuite) TestAuthorizationCodeRedeemedConcurrently() {
+	client := ts.createClient("confidential")
+
+	location := ts.authorize(url.Values{
+		"response_type": {"code"},
+		"client_id":     {client.ID.String()},
+		"redirect_uri":  {oauthTestRedirectURI},
+	})
+	redirectURL := ts.consent(location.Query().Get("authorization_id"), false)
+	code := redirectURL.Query().Get("code")
+
+	// both requests find the approved authorization, only one may exchange
+	// its code
+	var wg sync.WaitGroup
+	codes := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes <- ts.exchangeCode(url.Values{
+				"code":          {code},
+				"client_id":     {client.ID.String()},
+				"client_secret": {client.ClientSecret},
+				"redirect_uri":  {oauthTestRedirectURI},
+			}).Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+
+	var succeeded int
+	for code := range codes {
+		if code == http.StatusOK {
+			succeeded++
+		} else {
+			require.Equal(ts.T(), http.StatusBadRequest, code)
+		}
+	}
+	require.Equal(ts.T(), 1, succeeded)
+}
+
+func (ts *OAuthServerTestSuite) TestAuthorizeErrors() {
+	client := ts.createClient("public")
+
+	// unregistered redirect URIs are not redirected to
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/oauth/authorize?"+url.Values{
+		"response_type": {"code"},
+		"client_id":     {client.ID.String()},
+		"redirect_uri":  {"https://evil.example.com/callback"},
+	}.Encode(), nil)
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+
+	// public clients must use PKCE
+	location := ts.authorize(url.Values{
+		"response_type": {"code"},
+		"client_id":     {client.ID.String()},
+		"redirect_uri":  {oauthTestRedirectURI},
+		"state":         {"xyz"},
+	})
+	require.True(ts.T(), strings.HasPrefix(location.String(), oauthTestRedirectURI))
+	require.Equal(ts.T(), "invalid_request", location.Query().Get("error"))
+	require.Equal(ts.T(), "xyz", location.Query().Get("state"))
+
+	location = ts.authorize(url.Values{
+		"response_type":  {"code"},
+		"client_id":      {client.ID.String()},
+		"redirect_uri":   {oauthTestRedirectURI},
+		"scope":          {"openid admin"},
+		"code_challenge": {"challenge"},
+	})
+	require.Equal(ts.T(), "invalid_scope", location.Query().Get("error"))
+}
+
+func (ts *OAuthServerTestSuite) TestConsentDenied() {
+	client := ts.createClient("confidential")
+
+	location := ts.authorize(url.Values{
+		"response_type": {"code"},
+		"client_id":     {client.ID.String()},
+		"redirect_uri":  {oauthTestRedirectURI},
+	})
+	authorizationID := location.Query().Get("authorization_id")
+
+	redirectURL := ts.consent(authorizationID, true)
+	require.Equal(ts.T(), "access_denied", redirectURL.Query().Get("error"))
+	require.Empty(ts.T(), redirectURL.Query().Get("code"))
+
+	w := ts.userRequest(http.MethodGet, "/oauth/authorizations/"+authorizationID, nil)
+	require.Equal(ts.T(), http.StatusNotFound, w.Code)
+}
+
+func (ts *OAuthServerTestSuite) TestClientCredentialsGrant() {
+	client := ts.createClientWithParams(CreateOAuthClientParams{
+		Name:                     "Reporting Service",
+		ClientCredentialsEnabled: true,
+		Role:                     "service_account",
+		Claims: map[string]interface{}{
+			"tenant": "acme",
+		},
+	})
+
+	w := ts.exchangeClientCredentials(client.ID.String(), "wrong-secret")
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+
+	w = ts.exchangeClientCredentials(client.ID.String(), client.ClientSecret)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	var resp ClientCredentialsTokenResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&resp))
+	require.Equal(ts.T(), "bearer", resp.TokenType)
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(resp.Token, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(ts.Config.JWT.Secret), nil
+	})
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), client.ID.String(), claims["sub"])
+	require.Equal(ts.T(), "service_account", claims["role"])
+	require.Equal(ts.T(), "acme", claims["tenant"])
+
+	// client credentials grants are audited against the client, not a user
+	entries, err := models.FindAuditLogEntries(ts.API.db, []string{"action"}, string(models.ClientCredentialsGrantedAction), nil)
+	require.NoError(ts.T(), err)
+	require.Len(ts.T(), entries, 1)
+	require.Equal(ts.T(), "oauth_client", entries[0].Payload["actor_type"])
+	require.Equal(ts.T(), "192.0.2.1", entries[0].IPAddress)
+}
+
+func (ts *OAuthServerTestSuite) TestClientCredentialsGrantDefaultRole() {
+	client := ts.createClientWithParams(CreateOAuthClientParams{
+		Name:                     "Reporting Service",
+		ClientCredentialsEnabled: true,
+	})
+
+	w := ts.exchangeClientCredentials(client.ID.String(), client.ClientSecret)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	var resp ClientCredentialsTokenResponse
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&resp))
+
+	// service accounts don't get the role of signed in users
+	claims := ts.parseToken(resp.Token)
+	require.Equal(ts.T(), "service_account", claims["role"])
+}
+
+func (ts *OAuthServerTestSuite) TestClientCredentialsGrantNotEnabled() {
+	client := ts.createClient("confidential")
+
+	w := ts.exchangeClientCredentials(client.ID.String(), client.ClientSecret)
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+	data := &OAuthError{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(data))
+	require.Equal(ts.T(), "unauthorized_client", data.Err)
+}
+
+func (ts *OAuthServerTestSuite) exchangeClientCredentials(clientID, clientSecret string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/token", strings.NewReader(url.Values{
+		"grant_type": {"client_credentials"},
+	}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(clientID, clientSecret)
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	return w
+}
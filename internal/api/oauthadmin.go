@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi"
+	"github.com/gofrs/uuid"
+	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/observability"
+)
+
+// loadOAuthClient looks for a client_id parameter in the URL route and loads
+// the OAuth client with that ID and adds it to the context.
+func (a *API) loadOAuthClient(w http.ResponseWriter, r *http.Request) (context.Context, error) {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	clientID, err := uuid.FromString(chi.URLParam(r, "client_id"))
+	if err != nil {
+		return nil, notFoundError("OAuth client not found")
+	}
+
+	client, err := models.FindOAuthClientByID(db, clientID)
+	if err != nil {
+		if models.IsNotFoundError(err) {
+			return nil, notFoundError("OAuth client not found")
+		}
+		return nil, internalServerError("Database error finding OAuth client").WithInternalError(err)
+	}
+
+	observability.LogEntrySetField(r, "oauth_client_id", client.ID.String())
+
+	return withOAuthClient(ctx, client), nil
+}
+
+type CreateOAuthClientParams struct {
+	Name         string   `json:"name"`
+	ClientType   string   `json:"client_type"`
+	RedirectURIs []string `json:"redirect_uris"`
+
+	ClientCredentialsEnabled bool                   `json:"client_credentials_enabled"`
+	Role                     string                 `json:"role"`
+	Claims                   map[string]interface{} `json:"claims"`
+}
+
+// OAuthClientResponse is returned when a client is registered. The client
+// secret is only ever included in this response.
+type OAuthClientResponse struct {
+	*models.OAuthClient
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+func (p *CreateOAuthClientParams) validate() (models.OAuthClientType, error) {
+	if p.Name == "" {
+		return 0, badRequestError("OAuth client requires a name")
+	}
+
+	var clientType models.OAuthClientType
+	switch p.ClientType {
+	case models.OAuthClientPublic.String():
+		clientType = models.OAuthClientPublic
+	case "", models.OAuthClientConfidential.String():
+		clientType = models.OAuthClientConfidential
+	default:
+		return 0, badRequestError("client_type must be either 'public' or 'confidential'")
+	}
+
+	if p.ClientCredentialsEnabled && clientType != models.OAuthClientConfidential {
+		return 0, badRequestError("Only confidential clients can use the client credentials grant")
+	}
+	for claim := range p.Claims {
+		if clientCredentialsReservedClaims[claim] {
+			return 0, badRequestError("claims must not set the reserved claim %q", claim)
+		}
+	}
+
+	// service accounts that only use the client credentials grant don't
+	// need a redirect URI
+	if len(p.RedirectURIs) == 0 && !p.ClientCredentialsEnabled {
+		return 0, badRequestError("OAuth client requires at least one redirect URI")
+	}
+	for _, redirectURI := range p.RedirectURIs {
+		u, err := url.ParseRequestURI(redirectURI)
+		if err != nil || u.Scheme == "" {
+			return 0, badRequestError("redirect_uris contains an invalid URL %q", redirectURI)
+		}
+		if u.Fragment != "" {
+			return 0, badRequestError("redirect_uris must not contain a fragment %q", redirectURI)
+		}
+	}
+
+	return clientType, nil
+}
+
+// adminOAuthClientsList lists all registered OAuth clients. Does not deal
+// with pagination at this time.
+func (a *API) adminOAuthClientsList(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	clients, err := models.FindAllOAuthClients(db)
+	if err != nil {
+		return internalServerError("Database error finding OAuth clients").WithInternalError(err)
+	}
+
+	return sendJSON(w, http.StatusOK, map[string]interface{}{
+		"items": clients,
+	})
+}
+
+// adminOAuthClientsCreate registers a new OAuth client.
+func (a *API) adminOAuthClientsCreate(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	body, err := getBodyBytes(r)
+	if err != nil {
+		return internalServerError("Unable to read request body").WithInternalError(err)
+	}
+
+	var params CreateOAuthClientParams
+	if err := json.Unmarshal(body, &params); err != nil {
+		return badRequestError("Unable to parse JSON").WithInternalError(err)
+	}
+
+	clientType, err := params.validate()
+	if err != nil {
+		return err
+	}
+
+	client, clientSecret := models.NewOAuthClient(params.Name, clientType, params.RedirectURIs)
+	client.ClientCredentialsEnabled = params.ClientCredentialsEnabled
+	if params.Role != "" {
+		client.Role = &params.Role
+	}
+	if params.Claims != nil {
+		client.Claims = params.Claims
+	}
+	if err := db.Create(client); err != nil {
+		return internalServerError("Database error creating OAuth client").WithInternalError(err)
+	}
+
+	return sendJSON(w, http.StatusCreated, &OAuthClientResponse{
+		OAuthClient:  client,
+		ClientSecret: clientSecret,
+	})
+}
+
+// adminOAuthClientsGet returns an existing OAuth client.
+func (a *API) adminOAuthClientsGet(w http.ResponseWriter, r *http.Request) error {
+	client := getOAuthClient(r.Context())
+
+	return sendJSON(w, http.StatusOK, client)
+}
+
+// adminOAuthClientsDelete removes an OAuth client, along with any pending
+// authorizations for it.
+func (a *API) adminOAuthClientsDelete(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	db := a.db.WithContext(ctx)
+
+	client := getOAuthClient(ctx)
+	if err := db.Destroy(client); err != nil {
+		return internalServerError("Database error deleting OAuth client").WithInternalError(err)
+	}
+
+	return sendJSON(w, http.StatusOK, client)
+}
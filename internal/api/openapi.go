@@ -0,0 +1,22 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+//go:generate go run ../../hack/openapi2json ../../openapi.yaml openapi.json
+
+// openAPISpec is the OpenAPI specification in openapi.yaml at the root of the
+// repository, converted to JSON by go generate.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// OpenAPISpec serves the OpenAPI specification of the API.
+func (a *API) OpenAPISpec(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write(openAPISpec)
+	return err
+}
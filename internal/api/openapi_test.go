@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/conf"
+)
+
+var openAPIPathParamRegexp = regexp.MustCompile(`\{[^}]+\}`)
+
+var openAPIMethods = map[string]bool{
+	http.MethodGet:    true,
+	http.MethodPost:   true,
+	http.MethodPut:    true,
+	http.MethodPatch:  true,
+	http.MethodDelete: true,
+}
+
+// normalizeOpenAPIPath makes chi route patterns and OpenAPI paths comparable,
+// e.g. /*/admin/*/users/*/{user_id}/*/ and /admin/users/{userId}.
+func normalizeOpenAPIPath(path string) string {
+	path = strings.ReplaceAll(path, "/*", "")
+	path = strings.TrimSuffix(path, "/")
+	path = openAPIPathParamRegexp.ReplaceAllString(path, "{}")
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	config, err := conf.LoadGlobal(apiTestConfig)
+	require.NoError(t, err)
+	api := NewAPIWithVersion(context.Background(), config, nil, apiTestVersion)
+
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	w := httptest.NewRecorder()
+	api.handler.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var spec struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&spec))
+	assert.Equal(t, "3.0.3", spec.OpenAPI)
+
+	documented := map[string]bool{}
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			// path items also hold common parameters, a summary etc.
+			if method = strings.ToUpper(method); openAPIMethods[method] {
+				documented[method+" "+normalizeOpenAPIPath(path)] = true
+			}
+		}
+	}
+
+	// every route served by the API has to be documented
+	err = chi.Walk(api.routes, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		operation := method + " " + normalizeOpenAPIPath(route)
+		assert.True(t, documented[operation], "%s is missing from openapi.yaml", operation)
+		delete(documented, operation)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// and every documented operation has to be served
+	for operation := range documented {
+		assert.Fail(t, "openapi.yaml documents an operation that isn't served", "%s isn't served", operation)
+	}
+}
+
+func TestOpenAPISpecIsGenerated(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not installed")
+	}
+
+	generated := filepath.Join(t.TempDir(), "openapi.json")
+	out, err := exec.Command("go", "run", "../../hack/openapi2json", "../../openapi.yaml", generated).CombinedOutput() // #nosec G204
+	require.NoError(t, err, string(out))
+
+	spec, err := os.ReadFile(generated)
+	require.NoError(t, err)
+	require.Equal(t, string(spec), string(openAPISpec), "openapi.json is out of date, run go generate ./internal/api")
+}
@@ -151,7 +151,7 @@ func (a *API) SmsOtp(w http.ResponseWriter, r *http.Request) error {
 		// Sign them up with temporary password.
 		password, err := password.Generate(64, 10, 0, false, true)
 		if err != nil {
-			internalServerError("error creating user").WithInternalError(err)
+			return internalServerError("error creating user").WithInternalError(err)
 		}
 
 		signUpParams := &SignupParams{
@@ -205,7 +205,7 @@ func (a *API) SmsOtp(w http.ResponseWriter, r *http.Request) error {
 		}
 		mID, serr := a.sendPhoneConfirmation(ctx, tx, user, params.Phone, phoneConfirmationOtp, smsProvider, params.Channel)
 		if serr != nil {
-			return badRequestError("Error sending sms OTP: %v", err)
+			return badRequestError("Error sending sms OTP: %v", serr)
 		}
 		messageID = mID
 		return nil
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/didip/tollbooth/v5"
+	"github.com/didip/tollbooth/v5/limiter"
+	"github.com/sirupsen/logrus"
+	"github.com/supabase/auth/internal/conf"
+)
+
+// configReloader serves requests with the API built from the latest
+// configuration. It's shared by all APIs it swapped in, so that a reload
+// triggered through any of them replaces the current one.
+type configReloader struct {
+	mu         sync.Mutex
+	configFile string
+	current    atomic.Pointer[API]
+
+	// baseCtx and opts are what the first API was created with
+	baseCtx context.Context
+	opts    []Option
+
+	// limiters are kept across reloads, so that reloading doesn't reset
+	// the rate limits
+	limitersMu sync.Mutex
+	limiters   map[string]*limiter.Limiter
+}
+
+func (c *configReloader) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.current.Load().handler.ServeHTTP(w, r)
+}
+
+// SetConfigFile sets the file ReloadConfig loads the configuration from, the
+// same as for conf.LoadGlobal.
+func (a *API) SetConfigFile(filename string) {
+	a.reloader.mu.Lock()
+	defer a.reloader.mu.Unlock()
+
+	a.reloader.configFile = filename
+}
+
+// ReloadConfig loads and validates the configuration again, and swaps in an
+// API using it for new requests. In-flight requests finish with the previous
+// configuration. If the configuration is invalid the current one is kept.
+//
+// The database connection and the listening address are not reloaded, and
+// the rate limiters are kept unless their limits changed.
+func (a *API) ReloadConfig() error {
+	reloader := a.reloader
+	reloader.mu.Lock()
+	defer reloader.mu.Unlock()
+
+	config, err := conf.ReloadGlobal(reloader.configFile)
+	if err != nil {
+		return err
+	}
+
+	current := reloader.current.Load()
+	reloader.current.Store(newAPI(reloader, config, current.db, current.version))
+
+	logrus.WithField("component", "api").Info("configuration reloaded")
+
+	return nil
+}
+
+// rateLimiter returns the limiter called name of the current API if its rate
+// and burst are unchanged, so that its counts carry over, and a new one
+// otherwise. The rate is max requests per second.
+func (a *API) rateLimiter(name string, max float64, burst int, ttl time.Duration) *limiter.Limiter {
+	reloader := a.reloader
+	reloader.limitersMu.Lock()
+	defer reloader.limitersMu.Unlock()
+
+	if l, ok := reloader.limiters[name]; ok && l.GetMax() == max && l.GetBurst() == burst {
+		return l
+	}
+
+	l := tollbooth.NewLimiter(max, &limiter.ExpirableOptions{
+		DefaultExpirationTTL: ttl,
+	}).SetBurst(burst)
+	if reloader.limiters == nil {
+		reloader.limiters = map[string]*limiter.Limiter{}
+	}
+	reloader.limiters[name] = l
+	return l
+}
+
+// adminConfigReload reloads the configuration, see ReloadConfig.
+func (a *API) adminConfigReload(w http.ResponseWriter, r *http.Request) error {
+	if err := a.ReloadConfig(); err != nil {
+		return internalServerError("Error reloading configuration, the current configuration is kept").WithInternalError(err)
+	}
+
+	return sendJSON(w, http.StatusOK, map[string]interface{}{})
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/supabase/auth/internal/api/sms_provider"
@@ -18,6 +19,9 @@ type ResendConfirmationParams struct {
 	Type  string `json:"type"`
 	Email string `json:"email"`
 	Phone string `json:"phone"`
+
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
 }
 
 func (p *ResendConfirmationParams) Validate(config *conf.GlobalConfiguration) error {
@@ -36,6 +40,14 @@ func (p *ResendConfirmationParams) Validate(config *conf.GlobalConfiguration) er
 		return badRequestError("Type provided requires a phone number")
 	}
 
+	if err := validatePKCEParams(p.CodeChallengeMethod, p.CodeChallenge); err != nil {
+		return err
+	}
+	if p.CodeChallenge != "" && (p.Type == smsVerification || p.Type == phoneChangeVerification) {
+		// PKCE not needed as phone verifications already return access token in body
+		return badRequestError("PKCE not supported for phone verification")
+	}
+
 	var err error
 	if p.Email != "" && p.Phone != "" {
 		return badRequestError("Only an email address or phone number should be provided.")
@@ -120,6 +132,14 @@ func (a *API) Resend(w http.ResponseWriter, r *http.Request) error {
 		}
 	}
 
+	var codeChallengeMethod models.CodeChallengeMethod
+	flowType := getFlowFromChallenge(params.CodeChallenge)
+	if isPKCEFlow(flowType) {
+		if codeChallengeMethod, err = models.ParseCodeChallengeMethod(params.CodeChallengeMethod); err != nil {
+			return err
+		}
+	}
+
 	messageID := ""
 	mailer := a.Mailer(ctx)
 	referrer := utilities.GetReferrer(r, config)
@@ -130,8 +150,12 @@ func (a *API) Resend(w http.ResponseWriter, r *http.Request) error {
 			if terr := models.NewAuditLogEntry(r, tx, user, models.UserConfirmationRequestedAction, "", nil); terr != nil {
 				return terr
 			}
-			// PKCE not implemented yet
-			return sendConfirmation(tx, user, mailer, config.SMTP.MaxFrequency, referrer, externalURL, config.Mailer.OtpLength, models.ImplicitFlow)
+			if isPKCEFlow(flowType) {
+				if terr := models.NewFlowStateWithUserID(tx, "email", params.CodeChallenge, codeChallengeMethod, models.EmailSignup, &user.ID); terr != nil {
+					return terr
+				}
+			}
+			return sendConfirmation(tx, user, mailer, config.SMTP.MaxFrequency, referrer, externalURL, config.Mailer.OtpLength, flowType)
 		case smsVerification:
 			if terr := models.NewAuditLogEntry(r, tx, user, models.UserRecoveryRequestedAction, "", nil); terr != nil {
 				return terr
@@ -146,7 +170,12 @@ func (a *API) Resend(w http.ResponseWriter, r *http.Request) error {
 			}
 			messageID = mID
 		case emailChangeVerification:
-			return a.sendEmailChange(tx, config, user, mailer, user.EmailChange, referrer, externalURL, config.Mailer.OtpLength, models.ImplicitFlow)
+			if isPKCEFlow(flowType) {
+				if terr := models.NewFlowStateWithUserID(tx, models.EmailChange.String(), params.CodeChallenge, codeChallengeMethod, models.EmailChange, &user.ID); terr != nil {
+					return terr
+				}
+			}
+			return a.sendEmailChange(tx, config, user, mailer, user.EmailChange, referrer, externalURL, config.Mailer.OtpLength, flowType)
 		case phoneChangeVerification:
 			smsProvider, terr := sms_provider.GetSmsProvider(*config)
 			if terr != nil {
@@ -162,8 +191,9 @@ func (a *API) Resend(w http.ResponseWriter, r *http.Request) error {
 	})
 	if err != nil {
 		if errors.Is(err, MaxFrequencyLimitError) {
-			until := time.Until(user.ConfirmationSentAt.Add(config.SMTP.MaxFrequency)) / time.Second
-			return tooManyRequestsError("For security purposes, you can only request this once every %d seconds.", until)
+			until := resendRetryAfter(config, user, params.Type) / time.Second
+			w.Header().Set("Retry-After", strconv.Itoa(int(until)))
+			return tooManyRequestsError("For security purposes, you can only request this after %d seconds.", until)
 		}
 		return internalServerError("Unable to process request").WithInternalError(err)
 	}
@@ -175,3 +205,31 @@ func (a *API) Resend(w http.ResponseWriter, r *http.Request) error {
 
 	return sendJSON(w, http.StatusOK, ret)
 }
+
+// resendRetryAfter returns how long until the type can be resent, as each
+// type is rate limited separately by when it was last sent.
+func resendRetryAfter(config *conf.GlobalConfiguration, user *models.User, otpType string) time.Duration {
+	var sentAt *time.Time
+	maxFrequency := config.SMTP.MaxFrequency
+
+	switch otpType {
+	case signupVerification:
+		sentAt = user.ConfirmationSentAt
+	case emailChangeVerification:
+		sentAt = user.EmailChangeSentAt
+	case smsVerification:
+		sentAt = user.ConfirmationSentAt
+		maxFrequency = config.Sms.MaxFrequency
+	case phoneChangeVerification:
+		sentAt = user.PhoneChangeSentAt
+		maxFrequency = config.Sms.MaxFrequency
+	}
+
+	if sentAt == nil {
+		return 0
+	}
+	if until := time.Until(sentAt.Add(maxFrequency)); until > 0 {
+		return until
+	}
+	return 0
+}
@@ -210,3 +210,65 @@ func (ts *ResendTestSuite) TestResendSuccess() {
 		})
 	}
 }
+
+func (ts *ResendTestSuite) TestResendPKCE() {
+	u, err := models.NewUser("", "foo@example.com", "password", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err, "Error creating test user model")
+	// Avoid max freq limit error
+	now := time.Now().Add(-1 * time.Minute)
+	u.ConfirmationToken = "123456"
+	u.ConfirmationSentAt = &now
+	require.NoError(ts.T(), ts.API.db.Create(u), "Error saving new test user")
+
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+		"type":                  "signup",
+		"email":                 u.GetEmail(),
+		"code_challenge":        "ozSI5aWmN2sJtLQBNpYd3Ma7rmBmH3RYdFyMv2--nVs",
+		"code_challenge_method": "s256",
+	}))
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/resend", &buffer)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code)
+
+	dbUser, err := models.FindUserByID(ts.API.db, u.ID)
+	require.NoError(ts.T(), err)
+	require.Contains(ts.T(), dbUser.ConfirmationToken, models.PKCEFlow.String()+"_")
+
+	flowState, err := models.FindFlowStateByUserID(ts.API.db, u.ID.String(), models.EmailSignup)
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), models.SHA256.String(), flowState.CodeChallengeMethod)
+}
+
+func (ts *ResendTestSuite) TestResendRateLimitedPerType() {
+	maxFrequency := ts.Config.SMTP.MaxFrequency
+	ts.Config.SMTP.MaxFrequency = time.Minute
+	defer func() {
+		ts.Config.SMTP.MaxFrequency = maxFrequency
+	}()
+
+	// the confirmation was never sent, only the email change
+	now := time.Now()
+	u, err := models.NewUser("", "foo@example.com", "password", ts.Config.JWT.Aud, nil)
+	require.NoError(ts.T(), err, "Error creating test user model")
+	u.EmailChange = "bar@example.com"
+	u.EmailChangeSentAt = &now
+	u.EmailChangeTokenNew = "123456"
+	require.NoError(ts.T(), ts.API.db.Create(u), "Error saving new test user")
+
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+		"type":  emailChangeVerification,
+		"email": u.GetEmail(),
+	}))
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/resend", &buffer)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusTooManyRequests, w.Code)
+	require.NotEmpty(ts.T(), w.Header().Get("Retry-After"))
+}
@@ -207,10 +207,14 @@ func (a *API) Signup(w http.ResponseWriter, r *http.Request) error {
 				return terr
 			}
 			user.Identities = []models.Identity{*identity}
+
+			if terr = a.requestSignupApproval(r, tx, user); terr != nil {
+				return terr
+			}
 		}
 
 		if params.Provider == "email" && !user.IsConfirmed() {
-			if config.Mailer.Autoconfirm {
+			if config.Mailer.ShouldAutoconfirm(user.GetEmail()) {
 				if terr = models.NewAuditLogEntry(r, tx, user, models.UserSignedUpAction, "", map[string]interface{}{
 					"provider": params.Provider,
 				}); terr != nil {
@@ -294,7 +298,7 @@ func (a *API) Signup(w http.ResponseWriter, r *http.Request) error {
 			if err != nil {
 				return err
 			}
-			if config.Mailer.Autoconfirm || config.Sms.Autoconfirm {
+			if config.Mailer.ShouldAutoconfirm(params.Email) || config.Sms.Autoconfirm {
 				return badRequestError("User already registered")
 			}
 			sanitizedUser, err := sanitizeUser(user, params)
@@ -307,7 +311,8 @@ func (a *API) Signup(w http.ResponseWriter, r *http.Request) error {
 	}
 
 	// handles case where Mailer.Autoconfirm is true or Phone.Autoconfirm is true
-	if user.IsConfirmed() || user.IsPhoneConfirmed() {
+	// signups held for approval get a session once they are approved
+	if (user.IsConfirmed() || user.IsPhoneConfirmed()) && !user.IsPendingApproval() {
 		var token *AccessTokenResponse
 		err = db.Transaction(func(tx *storage.Connection) error {
 			var terr error
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"fmt"
@@ -32,8 +33,19 @@ type AccessTokenClaims struct {
 	AuthenticatorAssuranceLevel   string                 `json:"aal,omitempty"`
 	AuthenticationMethodReference []models.AMREntry      `json:"amr,omitempty"`
 	SessionId                     string                 `json:"session_id,omitempty"`
+	Scope                         string                 `json:"scope,omitempty"`
+	ClientID                      string                 `json:"client_id,omitempty"`
+	// AuthorizedParty is only set in ID tokens, which are rejected as
+	// access tokens.
+	AuthorizedParty string `json:"azp,omitempty"`
 }
 
+// profileUpdateScope limits access tokens of users with an incomplete
+// profile to reading and updating the user.
+const profileUpdateScope = "profile_update"
+
+const profileIncompleteMsg = "Complete the required profile fields first"
+
 // AccessTokenResponse represents an OAuth2 success response
 type AccessTokenResponse struct {
 	Token                string       `json:"access_token"`
@@ -41,9 +53,17 @@ type AccessTokenResponse struct {
 	ExpiresIn            int          `json:"expires_in"`
 	ExpiresAt            int64        `json:"expires_at"`
 	RefreshToken         string       `json:"refresh_token"`
-	User                 *models.User `json:"user"`
+	User                 *models.User `json:"user,omitempty"`
 	ProviderAccessToken  string       `json:"provider_token,omitempty"`
 	ProviderRefreshToken string       `json:"provider_refresh_token,omitempty"`
+
+	// ProfileIncomplete is set when the user is missing required profile
+	// fields and the access token is limited to updating the profile.
+	ProfileIncomplete bool `json:"profile_incomplete,omitempty"`
+
+	// IDToken is issued to third-party OAuth clients that requested the
+	// openid scope.
+	IDToken string `json:"id_token,omitempty"`
 }
 
 // AsRedirectURL encodes the AccessTokenResponse as a redirect URL that
@@ -54,6 +74,9 @@ func (r *AccessTokenResponse) AsRedirectURL(redirectURL string, extraParams url.
 	extraParams.Set("expires_in", strconv.Itoa(r.ExpiresIn))
 	extraParams.Set("expires_at", strconv.FormatInt(r.ExpiresAt, 10))
 	extraParams.Set("refresh_token", r.RefreshToken)
+	if r.ProfileIncomplete {
+		extraParams.Set("profile_incomplete", "true")
+	}
 
 	return redirectURL + "#" + extraParams.Encode()
 }
@@ -63,6 +86,31 @@ type PasswordGrantParams struct {
 	Email    string `json:"email"`
 	Phone    string `json:"phone"`
 	Password string `json:"password"`
+
+	// Identifier is either an email address or a phone number, for clients
+	// with a single login field.
+	Identifier string `json:"identifier"`
+}
+
+// resolveIdentifier sets the email or phone number from the identifier,
+// based on its format.
+func (p *PasswordGrantParams) resolveIdentifier() error {
+	if p.Identifier == "" {
+		return nil
+	}
+	if p.Email != "" || p.Phone != "" {
+		return unprocessableEntityError("Only an identifier, email address or phone number should be provided on login.")
+	}
+
+	identifier := strings.TrimSpace(p.Identifier)
+	if strings.Contains(identifier, "@") {
+		p.Email = identifier
+	} else if validateE164Format(formatPhoneNumber(identifier)) {
+		p.Phone = identifier
+	} else {
+		return oauthError("invalid_grant", InvalidLoginMessage)
+	}
+	return nil
 }
 
 // PKCEGrantParams are the parameters the PKCEGrant method accepts
@@ -87,7 +135,16 @@ func (a *API) Token(w http.ResponseWriter, r *http.Request) error {
 		return a.IdTokenGrant(ctx, w, r)
 	case "pkce":
 		return a.PKCE(ctx, w, r)
+	case deviceCodeGrantType, "device_code":
+		return a.DeviceCodeGrant(ctx, w, r)
+	case "authorization_code":
+		return a.OAuthAuthorizationCodeGrant(ctx, w, r)
+	case "client_credentials":
+		return a.ClientCredentialsGrant(ctx, w, r)
 	default:
+		if customGrantType, ok := getGrantType(grantType); ok {
+			return a.CustomGrant(ctx, w, r, grantType, customGrantType)
+		}
 		return oauthError("unsupported_grant_type", "")
 	}
 }
@@ -107,6 +164,10 @@ func (a *API) ResourceOwnerPasswordGrant(ctx context.Context, w http.ResponseWri
 		return badRequestError("Could not read password grant params: %v", err)
 	}
 
+	if err := params.resolveIdentifier(); err != nil {
+		return err
+	}
+
 	aud := a.requestAud(ctx, r)
 	config := a.config
 
@@ -291,9 +352,11 @@ func (a *API) generateAccessToken(ctx context.Context, tx *storage.Connection, u
 	config := a.config
 	aal, amr := models.AAL1.String(), []models.AMREntry{}
 	sid := ""
+	var session *models.Session
 	if sessionId != nil {
+		var terr error
 		sid = sessionId.String()
-		session, terr := models.FindSessionByID(tx, *sessionId, false)
+		session, terr = models.FindSessionByID(tx, *sessionId, false)
 		if terr != nil {
 			return "", 0, terr
 		}
@@ -323,6 +386,18 @@ func (a *API) generateAccessToken(ctx context.Context, tx *storage.Connection, u
 		AuthenticatorAssuranceLevel:   aal,
 		AuthenticationMethodReference: amr,
 	}
+	if session != nil && session.OAuthClientID != nil {
+		// access tokens of third-party OAuth clients are issued for the
+		// client and limited to the scopes the user granted
+		claims.Audience = session.OAuthClientID.String()
+		claims.ClientID = session.OAuthClientID.String()
+		if session.OAuthScope != nil {
+			claims.Scope = *session.OAuthScope
+		}
+	} else if config.Profile.IsIncomplete(user.UserMetaData) {
+		claims.Scope = profileUpdateScope
+		claims.Role = config.Profile.IncompleteRole
+	}
 
 	var token *jwt.Token
 	if config.Hook.CustomAccessToken.Enabled {
@@ -365,6 +440,10 @@ func (a *API) generateAccessToken(ctx context.Context, tx *storage.Connection, u
 func (a *API) issueRefreshToken(ctx context.Context, conn *storage.Connection, user *models.User, authenticationMethod models.AuthenticationMethod, grantParams models.GrantParams) (*AccessTokenResponse, error) {
 	config := a.config
 
+	if user.IsPendingApproval() {
+		return nil, forbiddenError(signupPendingApprovalMsg)
+	}
+
 	now := time.Now()
 	user.LastSignInAt = &now
 
@@ -401,12 +480,13 @@ func (a *API) issueRefreshToken(ctx context.Context, conn *storage.Connection, u
 	}
 
 	return &AccessTokenResponse{
-		Token:        tokenString,
-		TokenType:    "bearer",
-		ExpiresIn:    config.JWT.Exp,
-		ExpiresAt:    expiresAt,
-		RefreshToken: refreshToken.Token,
-		User:         user,
+		Token:             tokenString,
+		TokenType:         "bearer",
+		ExpiresIn:         config.JWT.Exp,
+		ExpiresAt:         expiresAt,
+		RefreshToken:      refreshToken.Token,
+		User:              user,
+		ProfileIncomplete: config.Profile.IsIncomplete(user.UserMetaData),
 	}, nil
 }
 
@@ -461,12 +541,13 @@ func (a *API) updateMFASessionAndClaims(r *http.Request, tx *storage.Connection,
 		return nil, err
 	}
 	return &AccessTokenResponse{
-		Token:        tokenString,
-		TokenType:    "bearer",
-		ExpiresIn:    config.JWT.Exp,
-		ExpiresAt:    expiresAt,
-		RefreshToken: refreshToken.Token,
-		User:         user,
+		Token:             tokenString,
+		TokenType:         "bearer",
+		ExpiresIn:         config.JWT.Exp,
+		ExpiresAt:         expiresAt,
+		RefreshToken:      refreshToken.Token,
+		User:              user,
+		ProfileIncomplete: config.Profile.IsIncomplete(user.UserMetaData),
 	}, nil
 }
 
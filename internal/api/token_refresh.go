@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/didip/tollbooth/v5"
+	"github.com/gofrs/uuid"
 	"github.com/supabase/auth/internal/metering"
 	"github.com/supabase/auth/internal/models"
 	"github.com/supabase/auth/internal/storage"
@@ -18,6 +20,11 @@ const retryLoopDuration = 5.0
 // RefreshTokenGrantParams are the parameters the RefreshTokenGrant method accepts
 type RefreshTokenGrantParams struct {
 	RefreshToken string `json:"refresh_token"`
+
+	// FactorID and Code verify the session with MFA again, when the MFA
+	// step-up policy returned mfa_required.
+	FactorID *uuid.UUID `json:"factor_id"`
+	Code     string     `json:"code"`
 }
 
 // RefreshTokenGrant implements the refresh_token grant type flow
@@ -51,7 +58,13 @@ func (a *API) RefreshTokenGrant(ctx context.Context, w http.ResponseWriter, r *h
 	for retry && time.Since(retryStart).Seconds() < retryLoopDuration {
 		retry = false
 
-		user, token, session, err := models.FindUserWithRefreshToken(db, params.RefreshToken, false)
+		// the checks before locking the refresh token can read from a
+		// replica, a token that was just issued may not have been
+		// replicated yet though
+		user, token, session, err := models.FindUserWithRefreshToken(a.readDB(ctx), params.RefreshToken, false)
+		if a.replica != nil && models.IsNotFoundError(err) {
+			user, token, session, err = models.FindUserWithRefreshToken(db, params.RefreshToken, false)
+		}
 		if err != nil {
 			if models.IsNotFoundError(err) {
 				return oauthError("invalid_grant", "Invalid Refresh Token: Refresh Token Not Found")
@@ -59,22 +72,26 @@ func (a *API) RefreshTokenGrant(ctx context.Context, w http.ResponseWriter, r *h
 			return internalServerError(err.Error())
 		}
 
-		if user.IsBanned() {
-			return oauthError("invalid_grant", "Invalid Refresh Token: User Banned")
+		if err := a.checkRefreshTokenSession(user, token, session, retryStart); err != nil {
+			return err
 		}
 
+		var stepUpFactor *models.Factor
+		var stepUpChallenge *models.Challenge
 		if session != nil {
-			result := session.CheckValidity(retryStart, &token.UpdatedAt, config.Sessions.Timebox, config.Sessions.InactivityTimeout)
-
-			switch result {
-			case models.SessionValid:
-				// do nothing
-
-			case models.SessionTimedOut:
-				return oauthError("invalid_grant", "Invalid Refresh Token: Session Expired (Inactivity)")
-
-			default:
-				return oauthError("invalid_grant", "Invalid Refresh Token: Session Expired")
+			if a.requiresStepUp(user, session) {
+				if params.FactorID == nil {
+					// the refresh token stays valid, the session
+					// continues once the user verifies with MFA
+					// again
+					return mfaRequiredError(user)
+				}
+				if err := a.limitStepUp(r, *params.FactorID); err != nil {
+					return err
+				}
+				if stepUpFactor, stepUpChallenge, err = a.verifyStepUp(ctx, r, user, *params.FactorID, params.Code); err != nil {
+					return err
+				}
 			}
 		}
 
@@ -107,6 +124,15 @@ func (a *API) RefreshTokenGrant(ctx context.Context, w http.ResponseWriter, r *h
 				return internalServerError(terr.Error())
 			}
 
+			// the checks above may have read stale rows from the
+			// replica, so they're repeated on the locked rows
+			if terr := a.checkRefreshTokenSession(user, token, session, retryStart); terr != nil {
+				return terr
+			}
+			if session != nil && stepUpFactor == nil && a.requiresStepUp(user, session) {
+				return mfaRequiredError(user)
+			}
+
 			if a.config.Sessions.SinglePerUser {
 				sessions, terr := models.FindAllSessionsForUser(tx, user.ID, true /* forUpdate */)
 				if models.IsNotFoundError(terr) {
@@ -167,21 +193,23 @@ func (a *API) RefreshTokenGrant(ctx context.Context, w http.ResponseWriter, r *h
 			var issuedToken *models.RefreshToken
 
 			if token.Revoked {
-				activeRefreshToken, terr := session.FindCurrentlyActiveRefreshToken(tx)
+				childRefreshToken, terr := models.FindChildRefreshToken(tx, token)
 				if terr != nil && !models.IsNotFoundError(terr) {
 					return internalServerError(terr.Error())
 				}
 
-				if activeRefreshToken != nil && activeRefreshToken.Parent.String() == token.Token {
-					// Token was revoked, but it's the
-					// parent of the currently active one.
+				if childRefreshToken != nil {
+					// Token was revoked, but the token it
+					// was swapped for is still valid.
 					// This indicates that the client was
 					// not able to store the result when it
-					// refreshed token. This case is
+					// refreshed token, or that it was
+					// refreshed concurrently. This case is
 					// allowed, provided we return back the
-					// active refresh token instead of
-					// creating a new one.
-					issuedToken = activeRefreshToken
+					// token it was swapped for instead of
+					// creating a new one, so the swap is
+					// idempotent on the locked token.
+					issuedToken = childRefreshToken
 				} else {
 					// For a revoked refresh token to be reused, it
 					// has to fall within the reuse interval.
@@ -208,6 +236,12 @@ func (a *API) RefreshTokenGrant(ctx context.Context, w http.ResponseWriter, r *h
 				return terr
 			}
 
+			if stepUpFactor != nil {
+				if terr = a.stepUpSession(r, tx, user, session, stepUpFactor, stepUpChallenge); terr != nil {
+					return terr
+				}
+			}
+
 			if issuedToken == nil {
 				newToken, terr := models.GrantRefreshTokenSwap(r, tx, user, token)
 				if terr != nil {
@@ -244,12 +278,19 @@ func (a *API) RefreshTokenGrant(ctx context.Context, w http.ResponseWriter, r *h
 			}
 
 			newTokenResponse = &AccessTokenResponse{
-				Token:        tokenString,
-				TokenType:    "bearer",
-				ExpiresIn:    config.JWT.Exp,
-				ExpiresAt:    expiresAt,
-				RefreshToken: issuedToken.Token,
-				User:         user,
+				Token:             tokenString,
+				TokenType:         "bearer",
+				ExpiresIn:         config.JWT.Exp,
+				ExpiresAt:         expiresAt,
+				RefreshToken:      issuedToken.Token,
+				User:              user,
+				ProfileIncomplete: config.Profile.IsIncomplete(user.UserMetaData),
+			}
+			if session.OAuthClientID != nil {
+				// third-party OAuth clients only get the claims of
+				// the granted scopes, not the user
+				newTokenResponse.User = nil
+				newTokenResponse.ProfileIncomplete = false
 			}
 			if terr = a.setCookieTokens(config, newTokenResponse, false, w); terr != nil {
 				return internalServerError("Failed to set JWT cookie. %s", terr)
@@ -278,3 +319,128 @@ func (a *API) RefreshTokenGrant(ctx context.Context, w http.ResponseWriter, r *h
 
 	return conflictError("Too many concurrent token refresh requests on the same session or refresh token")
 }
+
+// checkRefreshTokenSession checks that the user of the refresh token isn't
+// banned and its session is still valid.
+func (a *API) checkRefreshTokenSession(user *models.User, token *models.RefreshToken, session *models.Session, now time.Time) error {
+	config := a.config
+
+	if user.IsBanned() {
+		return oauthError("invalid_grant", "Invalid Refresh Token: User Banned")
+	}
+
+	if session == nil {
+		return nil
+	}
+
+	switch session.CheckValidity(now, &token.UpdatedAt, config.Sessions.Timebox, config.Sessions.InactivityTimeout) {
+	case models.SessionValid:
+		return nil
+
+	case models.SessionTimedOut:
+		return oauthError("invalid_grant", "Invalid Refresh Token: Session Expired (Inactivity)")
+
+	default:
+		return oauthError("invalid_grant", "Invalid Refresh Token: Session Expired")
+	}
+}
+
+// mfaRequiredError is returned when the MFA step-up policy requires the user
+// to verify with one of the listed factors before the session is refreshed.
+func mfaRequiredError(user *models.User) *OAuthError {
+	err := oauthError("mfa_required", "MFA verification is required to continue the session")
+	for _, factor := range user.Factors {
+		if factor.IsVerified() {
+			err.Factors = append(err.Factors, factor)
+		}
+	}
+	return err
+}
+
+// limitStepUp applies the rate limit of verifying a factor to step-up
+// attempts, which would otherwise only be limited like token refreshes. The
+// attempts on each factor are limited as well, regardless of where they
+// come from.
+func (a *API) limitStepUp(r *http.Request, factorID uuid.UUID) error {
+	config := a.config
+	lmt := a.rateLimiter("mfa_verify", config.MFA.RateLimitChallengeAndVerify/60, 30, time.Minute)
+
+	keys := [][]string{{"factor", factorID.String()}}
+	if limitHeader := config.RateLimitHeader; limitHeader != "" {
+		if key := r.Header.Get(limitHeader); key != "" {
+			keys = append(keys, []string{key})
+		}
+	}
+	for _, key := range keys {
+		if err := tollbooth.LimitByKeys(lmt, key); err != nil {
+			return httpError(http.StatusTooManyRequests, "Rate limit exceeded")
+		}
+	}
+	return nil
+}
+
+// verifyStepUp checks the code of a verified factor sent with the refresh
+// token. It returns the challenge recording the code, which can only be
+// used once.
+func (a *API) verifyStepUp(ctx context.Context, r *http.Request, user *models.User, factorID uuid.UUID, code string) (*models.Factor, *models.Challenge, error) {
+	for i := range user.Factors {
+		factor := &user.Factors[i]
+		if factor.ID == factorID && factor.IsVerified() {
+			if err := a.validateTOTPCode(ctx, user, factor, code); err != nil {
+				return nil, nil, err
+			}
+			timeStep, ok := totpTimeStep(code, factor.Secret, time.Now())
+			if !ok {
+				return nil, nil, badRequestError("Invalid TOTP code entered")
+			}
+			return factor, models.NewStepUpChallenge(factor, timeStep, utilities.GetIPAddress(r)), nil
+		}
+	}
+	return nil, nil, oauthError("invalid_grant", "Invalid MFA factor")
+}
+
+// stepUpSession records the MFA verification on the session, like verifying
+// a factor does, before the access token is issued.
+func (a *API) stepUpSession(r *http.Request, tx *storage.Connection, user *models.User, session *models.Session, factor *models.Factor, challenge *models.Challenge) error {
+	if _, err := models.FindChallengeByChallengeID(tx, challenge.ID); err == nil {
+		return badRequestError("TOTP code has already been used")
+	} else if !models.IsNotFoundError(err) {
+		return internalServerError("Database error finding challenge").WithInternalError(err)
+	}
+	if err := tx.Create(challenge); err != nil {
+		return internalServerError("Database error creating challenge").WithInternalError(err)
+	}
+	if err := models.NewAuditLogEntry(r, tx, user, models.VerifyFactorAction, r.RemoteAddr, map[string]interface{}{
+		"factor_id":    factor.ID,
+		"challenge_id": challenge.ID,
+	}); err != nil {
+		return err
+	}
+	if err := models.AddClaimToSession(tx, session.ID, models.TOTPSignIn); err != nil {
+		return err
+	}
+	if err := session.UpdateAssociatedFactor(tx, &factor.ID); err != nil {
+		return err
+	}
+	return session.UpdateAssociatedAAL(tx, models.AAL2.String())
+}
+
+// requiresStepUp checks the MFA step-up policy, which can require users with
+// verified factors to verify with MFA again before their session is refreshed.
+func (a *API) requiresStepUp(user *models.User, session *models.Session) bool {
+	config := a.config.MFA
+
+	if !config.StepUpRequireAAL2 && config.StepUpMaxAge == 0 {
+		return false
+	}
+	if !user.HasVerifiedFactors() {
+		return false
+	}
+
+	verifiedAt := session.LastMFAVerifiedAt()
+	if verifiedAt == nil || !session.IsAAL2() {
+		return config.StepUpRequireAAL2
+	}
+
+	return config.StepUpMaxAge > 0 && a.Now().After(verifiedAt.Add(config.StepUpMaxAge))
+}
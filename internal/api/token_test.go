@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
@@ -14,11 +15,14 @@ import (
 	"testing"
 	"time"
 
+	jwt "github.com/golang-jwt/jwt"
+	"github.com/pquerna/otp/totp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"github.com/supabase/auth/internal/conf"
 	"github.com/supabase/auth/internal/models"
+	"github.com/supabase/auth/internal/storage"
 )
 
 type TokenTestSuite struct {
@@ -180,6 +184,50 @@ func (ts *TokenTestSuite) TestFailedToSaveRefreshTokenResultCase() {
 	assert.Equal(ts.T(), firstResult.RefreshToken, secondResult.RefreshToken)
 }
 
+func (ts *TokenTestSuite) TestRefreshTokenSwapReturnsChild() {
+	originalSecurity := ts.API.config.Security
+
+	ts.API.config.Security.RefreshTokenRotationEnabled = true
+	ts.API.config.Security.RefreshTokenReuseInterval = 10
+
+	defer func() {
+		ts.API.config.Security = originalSecurity
+	}()
+
+	refresh := func(refreshToken string) string {
+		var buffer bytes.Buffer
+		require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+			"refresh_token": refreshToken,
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "http://localhost/token?grant_type=refresh_token", &buffer)
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		ts.API.handler.ServeHTTP(w, req)
+		require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+		var response struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+		require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&response))
+		return response.RefreshToken
+	}
+
+	first := refresh(ts.RefreshToken.Token)
+	second := refresh(first)
+
+	// reusing the original token within the reuse interval swaps it
+	// again, as the token it was swapped for has been swapped already
+	fork := refresh(ts.RefreshToken.Token)
+	require.NotEqual(ts.T(), second, fork)
+
+	// retrying the refresh of first returns the token first was swapped
+	// for, not the newest token of the session
+	assert.Equal(ts.T(), second, refresh(first))
+	assert.Equal(ts.T(), fork, refresh(ts.RefreshToken.Token))
+}
+
 func (ts *TokenTestSuite) TestSingleSessionPerUserNoTags() {
 	ts.API.config.Sessions.SinglePerUser = true
 	defer func() {
@@ -268,6 +316,185 @@ func (ts *TokenTestSuite) TestTokenPasswordGrantSuccess() {
 	assert.Equal(ts.T(), http.StatusOK, w.Code)
 }
 
+func (ts *TokenTestSuite) TestTokenPasswordGrantWithIncompleteProfile() {
+	ts.Config.Profile.RequiredFields = []string{"display_name"}
+	defer func() {
+		ts.Config.Profile.RequiredFields = nil
+	}()
+
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+		"email":    "test@example.com",
+		"password": "password",
+	}))
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/token?grant_type=password", &buffer)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	token := AccessTokenResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&token))
+	require.True(ts.T(), token.ProfileIncomplete)
+
+	roleOf := func(token string) string {
+		claims := AccessTokenClaims{}
+		_, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
+			return []byte(ts.Config.JWT.Secret), nil
+		})
+		require.NoError(ts.T(), err)
+		return claims.Role
+	}
+	require.Equal(ts.T(), "incomplete_profile", roleOf(token.Token))
+
+	update := func(params map[string]interface{}) *httptest.ResponseRecorder {
+		var buffer bytes.Buffer
+		require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(params))
+		req := httptest.NewRequest(http.MethodPut, "http://localhost/user", &buffer)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token.Token))
+		w := httptest.NewRecorder()
+		ts.API.handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// the limited token can't be used for anything but the profile
+	req = httptest.NewRequest(http.MethodGet, "http://localhost/reauthenticate", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token.Token))
+	w = httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusForbidden, w.Code, w.Body.String())
+
+	w = update(map[string]interface{}{"password": "newpassword"})
+	require.Equal(ts.T(), http.StatusForbidden, w.Code, w.Body.String())
+
+	w = update(map[string]interface{}{"data": map[string]interface{}{"display_name": "Test"}})
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	// refreshing issues an unrestricted token once the profile is complete
+	buffer.Reset()
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+		"refresh_token": token.RefreshToken,
+	}))
+	req = httptest.NewRequest(http.MethodPost, "http://localhost/token?grant_type=refresh_token", &buffer)
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	refreshed := AccessTokenResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&refreshed))
+	assert.False(ts.T(), refreshed.ProfileIncomplete)
+	u, err := models.FindUserByEmailAndAudience(ts.API.db, "test@example.com", ts.Config.JWT.Aud)
+	require.NoError(ts.T(), err)
+	assert.Equal(ts.T(), u.Role, roleOf(refreshed.Token))
+	assert.NotEqual(ts.T(), ts.Config.Profile.IncompleteRole, u.Role)
+}
+
+type testAssertionGrantType struct {
+	user *models.User
+}
+
+func (g *testAssertionGrantType) Authenticate(ctx context.Context, tx *storage.Connection, r *http.Request) (*models.User, error) {
+	if r.FormValue("assertion") != "valid-assertion" {
+		return nil, oauthError("invalid_grant", "Invalid assertion")
+	}
+	return g.user, nil
+}
+
+func (ts *TokenTestSuite) TestTokenCustomGrantType() {
+	RegisterGrantType("urn:example:assertion", &testAssertionGrantType{user: ts.User})
+	defer delete(grantTypes, "urn:example:assertion")
+
+	require.Panics(ts.T(), func() {
+		RegisterGrantType("password", &testAssertionGrantType{})
+	})
+	require.Panics(ts.T(), func() {
+		RegisterGrantType("urn:example:assertion", &testAssertionGrantType{})
+	})
+
+	cases := []struct {
+		desc      string
+		assertion string
+		expected  int
+	}{
+		{
+			desc:      "valid assertion",
+			assertion: "valid-assertion",
+			expected:  http.StatusOK,
+		},
+		{
+			desc:      "invalid assertion",
+			assertion: "invalid-assertion",
+			expected:  http.StatusBadRequest,
+		},
+	}
+
+	for _, c := range cases {
+		ts.Run(c.desc, func() {
+			req := httptest.NewRequest(http.MethodPost, "http://localhost/token?grant_type=urn:example:assertion&assertion="+c.assertion, nil)
+			w := httptest.NewRecorder()
+			ts.API.handler.ServeHTTP(w, req)
+			require.Equal(ts.T(), c.expected, w.Code, w.Body.String())
+
+			if c.expected == http.StatusOK {
+				token := AccessTokenResponse{}
+				require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&token))
+				assert.NotEmpty(ts.T(), token.RefreshToken)
+				assert.Equal(ts.T(), ts.User.ID, token.User.ID)
+			}
+		})
+	}
+}
+
+func (ts *TokenTestSuite) TestTokenPasswordGrantWithIdentifier() {
+	cases := []struct {
+		desc     string
+		params   map[string]interface{}
+		expected int
+	}{
+		{
+			desc: "email identifier",
+			params: map[string]interface{}{
+				"identifier": "test@example.com",
+				"password":   "password",
+			},
+			expected: http.StatusOK,
+		},
+		{
+			desc: "unrecognized identifier",
+			params: map[string]interface{}{
+				"identifier": "test",
+				"password":   "password",
+			},
+			expected: http.StatusBadRequest,
+		},
+		{
+			desc: "identifier with email",
+			params: map[string]interface{}{
+				"identifier": "test@example.com",
+				"email":      "test@example.com",
+				"password":   "password",
+			},
+			expected: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, c := range cases {
+		ts.Run(c.desc, func() {
+			var buffer bytes.Buffer
+			require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(c.params))
+
+			req := httptest.NewRequest(http.MethodPost, "http://localhost/token?grant_type=password", &buffer)
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			ts.API.handler.ServeHTTP(w, req)
+			assert.Equal(ts.T(), c.expected, w.Code, w.Body.String())
+		})
+	}
+}
+
 func (ts *TokenTestSuite) TestTokenRefreshTokenGrantSuccess() {
 	var buffer bytes.Buffer
 	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
@@ -282,6 +509,196 @@ func (ts *TokenTestSuite) TestTokenRefreshTokenGrantSuccess() {
 	assert.Equal(ts.T(), http.StatusOK, w.Code)
 }
 
+func (ts *TokenTestSuite) TestTokenRefreshTokenGrantWithReadReplica() {
+	// the replica is the database itself, so it's always in sync
+	ts.API.replica = ts.API.db
+	defer func() {
+		ts.API.replica = nil
+	}()
+
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+		"refresh_token": ts.RefreshToken.Token,
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/token?grant_type=refresh_token", &buffer)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	assert.Equal(ts.T(), http.StatusOK, w.Code)
+
+	data := &AccessTokenResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(data))
+	assert.NotEqual(ts.T(), ts.RefreshToken.Token, data.RefreshToken)
+}
+
+func (ts *TokenTestSuite) TestTokenRefreshTokenGrantWithStaleReadReplica() {
+	// a repeatable read transaction started before the user is banned acts
+	// as a replica that hasn't caught up yet
+	err := ts.API.db.Transaction(func(replica *storage.Connection) error {
+		require.NoError(ts.T(), replica.RawQuery("SET TRANSACTION ISOLATION LEVEL REPEATABLE READ").Exec())
+		_, err := models.FindUserByID(replica, ts.User.ID)
+		require.NoError(ts.T(), err)
+
+		u, err := models.FindUserByID(ts.API.db, ts.User.ID)
+		require.NoError(ts.T(), err)
+		require.NoError(ts.T(), u.Ban(ts.API.db, time.Hour))
+
+		ts.API.replica = replica
+		defer func() {
+			ts.API.replica = nil
+		}()
+
+		var buffer bytes.Buffer
+		require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+			"refresh_token": ts.RefreshToken.Token,
+		}))
+		req := httptest.NewRequest(http.MethodPost, "http://localhost/token?grant_type=refresh_token", &buffer)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		ts.API.handler.ServeHTTP(w, req)
+
+		// the ban is found on the locked rows of the primary
+		require.Equal(ts.T(), http.StatusBadRequest, w.Code, w.Body.String())
+		assert.Contains(ts.T(), w.Body.String(), "User Banned")
+		return nil
+	})
+	require.NoError(ts.T(), err)
+}
+
+func (ts *TokenTestSuite) TestTokenRefreshRequiresStepUp() {
+	ts.Config.MFA.StepUpRequireAAL2 = true
+	ts.Config.MFA.StepUpMaxAge = time.Hour
+	defer func() {
+		ts.Config.MFA.StepUpRequireAAL2 = false
+		ts.Config.MFA.StepUpMaxAge = 0
+		ts.API.overrideTime = nil
+	}()
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      ts.Config.SiteURL,
+		AccountName: ts.User.GetEmail(),
+	})
+	require.NoError(ts.T(), err)
+	f, err := models.NewFactor(ts.User, "testSimpleName", models.TOTP, models.FactorStateVerified, key.Secret())
+	require.NoError(ts.T(), err)
+	require.NoError(ts.T(), ts.API.db.Create(f))
+
+	request := func(method, path, token string, body map[string]interface{}) *httptest.ResponseRecorder {
+		var buffer bytes.Buffer
+		require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(body))
+		req := httptest.NewRequest(method, "http://localhost"+path, &buffer)
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		}
+
+		w := httptest.NewRecorder()
+		ts.API.handler.ServeHTTP(w, req)
+		return w
+	}
+	refresh := func(params map[string]interface{}) *httptest.ResponseRecorder {
+		return request(http.MethodPost, "/token?grant_type=refresh_token", "", params)
+	}
+	requireMFARequired := func(w *httptest.ResponseRecorder) {
+		require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+		data := &OAuthError{}
+		require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(data))
+		require.Equal(ts.T(), "mfa_required", data.Err)
+		// the verified factors are returned to verify with
+		require.Len(ts.T(), data.Factors, 1)
+		require.Equal(ts.T(), f.ID, data.Factors[0].ID)
+	}
+
+	// the session is aal1 but the user has a verified factor
+	requireMFARequired(refresh(map[string]interface{}{
+		"refresh_token": ts.RefreshToken.Token,
+	}))
+
+	// verifying the factor lets the session continue
+	accessToken, _, err := ts.API.generateAccessToken(context.Background(), ts.API.db, ts.User, ts.RefreshToken.SessionId, models.PasswordGrant)
+	require.NoError(ts.T(), err)
+	w := request(http.MethodPost, fmt.Sprintf("/factors/%s/challenge", f.ID), accessToken, map[string]interface{}{})
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	challenge := &ChallengeFactorResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(challenge))
+
+	code, err := totp.GenerateCode(key.Secret(), time.Now().UTC())
+	require.NoError(ts.T(), err)
+	w = request(http.MethodPost, fmt.Sprintf("/factors/%s/verify", f.ID), accessToken, map[string]interface{}{
+		"challenge_id": challenge.ID,
+		"code":         code,
+	})
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	token := &AccessTokenResponse{}
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(token))
+
+	w = refresh(map[string]interface{}{
+		"refresh_token": token.RefreshToken,
+	})
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(token))
+
+	// once the MFA verification is older than the max age, the session has
+	// to be verified again
+	ts.API.overrideTime = func() time.Time {
+		return time.Now().Add(ts.Config.MFA.StepUpMaxAge + time.Minute)
+	}
+	requireMFARequired(refresh(map[string]interface{}{
+		"refresh_token": token.RefreshToken,
+	}))
+
+	// which can be done with the refresh token, as the access token has
+	// likely expired by then
+	code, err = totp.GenerateCode(key.Secret(), time.Now().UTC())
+	require.NoError(ts.T(), err)
+	w = refresh(map[string]interface{}{
+		"refresh_token": token.RefreshToken,
+		"factor_id":     f.ID,
+		"code":          code[:len(code)-1] + string('0'+(code[len(code)-1]-'0'+1)%10),
+	})
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+
+	w = refresh(map[string]interface{}{
+		"refresh_token": token.RefreshToken,
+		"factor_id":     f.ID,
+		"code":          code,
+	})
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(token))
+
+	claims := &AccessTokenClaims{}
+	_, err = jwt.ParseWithClaims(token.Token, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(ts.Config.JWT.Secret), nil
+	})
+	require.NoError(ts.T(), err)
+	require.Equal(ts.T(), models.AAL2.String(), claims.AuthenticatorAssuranceLevel)
+
+	// the TOTP code can't be used again, even with another refresh token
+	w = refresh(map[string]interface{}{
+		"refresh_token": token.RefreshToken,
+		"factor_id":     f.ID,
+		"code":          code,
+	})
+	require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+	require.Contains(ts.T(), w.Body.String(), "already been used")
+
+	// step-up attempts are rate limited per factor like verifying it
+	for i := 0; i < 30; i++ {
+		w = refresh(map[string]interface{}{
+			"refresh_token": token.RefreshToken,
+			"factor_id":     f.ID,
+			"code":          "000000",
+		})
+		if w.Code == http.StatusTooManyRequests {
+			break
+		}
+		require.Equal(ts.T(), http.StatusBadRequest, w.Code)
+	}
+	require.Equal(ts.T(), http.StatusTooManyRequests, w.Code)
+}
+
 func (ts *TokenTestSuite) TestTokenPasswordGrantFailure() {
 	u := ts.createBannedUser()
 
@@ -103,6 +103,14 @@ func (a *API) UserUpdate(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
+	if claims := getClaims(ctx); claims != nil && claims.Scope == profileUpdateScope {
+		// tokens of users with an incomplete profile can only update the
+		// user metadata
+		if params.Email != "" || params.Password != nil || params.Phone != "" || params.Nonce != "" || params.AppData != nil {
+			return forbiddenError(profileIncompleteMsg)
+		}
+	}
+
 	if params.AppData != nil && !isAdmin(user, config) {
 		if !isAdmin(user, config) {
 			return unauthorizedError("Updating app_metadata requires admin privileges")
@@ -12,6 +12,7 @@ import (
 
 	"github.com/sethvargo/go-password/password"
 	"github.com/supabase/auth/internal/api/sms_provider"
+	"github.com/supabase/auth/internal/conf"
 	"github.com/supabase/auth/internal/crypto"
 	"github.com/supabase/auth/internal/models"
 	"github.com/supabase/auth/internal/observability"
@@ -39,6 +40,11 @@ const (
 // Only applicable when SECURE_EMAIL_CHANGE_ENABLED
 const singleConfirmationAccepted = "Confirmation link accepted. Please proceed to confirm link sent to the other email"
 
+const (
+	magicLinkUsedMsg    = "Magic link has already been used"
+	magicLinkExpiredMsg = "Magic link has expired"
+)
+
 // VerifyParams are the parameters the Verify endpoint accepts
 type VerifyParams struct {
 	Type       string `json:"type"`
@@ -165,7 +171,7 @@ func (a *API) verifyGet(w http.ResponseWriter, r *http.Request, params *VerifyPa
 				if err != nil {
 					return err
 				}
-				http.Redirect(w, r, rurl, http.StatusSeeOther)
+				a.redirectFromEmailLink(w, r, rurl)
 				return nil
 			}
 		default:
@@ -175,6 +181,11 @@ func (a *API) verifyGet(w http.ResponseWriter, r *http.Request, params *VerifyPa
 		if terr != nil {
 			return terr
 		}
+		if user.IsPendingApproval() {
+			// the confirmation is kept, but no session is issued until the
+			// signup is approved
+			return storage.NewCommitWithError(forbiddenError(signupPendingApprovalMsg))
+		}
 		if isImplicitFlow(flowType) {
 			token, terr = a.issueRefreshToken(ctx, tx, user, models.OTP, grantParams)
 
@@ -200,7 +211,7 @@ func (a *API) verifyGet(w http.ResponseWriter, r *http.Request, params *VerifyPa
 			if err != nil {
 				return err
 			}
-			http.Redirect(w, r, rurl, http.StatusSeeOther)
+			a.redirectFromEmailLink(w, r, rurl)
 			return nil
 		}
 	}
@@ -215,7 +226,7 @@ func (a *API) verifyGet(w http.ResponseWriter, r *http.Request, params *VerifyPa
 			return err
 		}
 	}
-	http.Redirect(w, r, rurl, http.StatusSeeOther)
+	a.redirectFromEmailLink(w, r, rurl)
 	return nil
 }
 
@@ -266,6 +277,9 @@ func (a *API) verifyPost(w http.ResponseWriter, r *http.Request, params *VerifyP
 		if terr != nil {
 			return terr
 		}
+		if user.IsPendingApproval() {
+			return storage.NewCommitWithError(forbiddenError(signupPendingApprovalMsg))
+		}
 		token, terr = a.issueRefreshToken(ctx, tx, user, models.OTP, grantParams)
 		if terr != nil {
 			return terr
@@ -320,6 +334,8 @@ func (a *API) signupVerify(r *http.Request, ctx context.Context, conn *storage.C
 			return terr
 		}
 
+		// confirming the user also applies the role and app metadata
+		// of an invite
 		if terr = user.Confirm(tx); terr != nil {
 			return internalServerError("Error confirming user").WithInternalError(terr)
 		}
@@ -362,6 +378,10 @@ func (a *API) recoverVerify(r *http.Request, ctx context.Context, conn *storage.
 	})
 
 	if err != nil {
+		var uerr models.RecoveryTokenUsedError
+		if errors.As(err, &uerr) {
+			return nil, expiredTokenError(magicLinkUsedMsg).WithInternalError(err)
+		}
 		return nil, internalServerError("Database error updating user").WithInternalError(err)
 	}
 	return user, nil
@@ -522,6 +542,11 @@ func (a *API) verifyTokenHash(ctx context.Context, conn *storage.Connection, par
 
 	if err != nil {
 		if models.IsNotFoundError(err) {
+			if isRecoveryTokenVerification(params.Type) {
+				if _, uerr := models.FindUserByMagicLinkUsedToken(conn, params.TokenHash); uerr == nil {
+					return nil, expiredTokenError(magicLinkUsedMsg).WithInternalError(err)
+				}
+			}
 			return nil, expiredTokenError("Email link is invalid or has expired").WithInternalError(err)
 		}
 		return nil, internalServerError("Database error finding user from email link").WithInternalError(err)
@@ -535,21 +560,26 @@ func (a *API) verifyTokenHash(ctx context.Context, conn *storage.Connection, par
 	switch params.Type {
 	case emailOTPVerification:
 		sentAt := user.ConfirmationSentAt
+		otpExp := config.Mailer.OtpExp
 		params.Type = "signup"
 		if user.RecoveryToken == params.TokenHash {
 			sentAt = user.RecoverySentAt
 			params.Type = "magiclink"
+			otpExp = recoveryOtpExp(user, &config.Mailer)
 		}
-		isExpired = isOtpExpired(sentAt, config.Mailer.OtpExp)
+		isExpired = isOtpExpired(sentAt, otpExp)
 	case signupVerification, inviteVerification:
-		isExpired = isOtpExpired(user.ConfirmationSentAt, config.Mailer.OtpExp)
+		isExpired = isOtpExpired(user.ConfirmationSentAt, confirmationOtpExp(user, config.Mailer.OtpExp))
 	case recoveryVerification, magicLinkVerification:
-		isExpired = isOtpExpired(user.RecoverySentAt, config.Mailer.OtpExp)
+		isExpired = isOtpExpired(user.RecoverySentAt, recoveryOtpExp(user, &config.Mailer))
 	case emailChangeVerification:
 		isExpired = isOtpExpired(user.EmailChangeSentAt, config.Mailer.OtpExp)
 	}
 
 	if isExpired {
+		if params.Type == magicLinkVerification {
+			return nil, expiredTokenError(magicLinkExpiredMsg).WithInternalMessage("magic link has expired")
+		}
 		return nil, expiredTokenError("Email link is invalid or has expired").WithInternalMessage("email link has expired")
 	}
 
@@ -597,16 +627,16 @@ func (a *API) verifyUserAndToken(ctx context.Context, conn *storage.Connection,
 		if isOtpValid(tokenHash, user.ConfirmationToken, user.ConfirmationSentAt, config.Mailer.OtpExp) {
 			isValid = true
 			params.Type = signupVerification
-		} else if isOtpValid(tokenHash, user.RecoveryToken, user.RecoverySentAt, config.Mailer.OtpExp) {
+		} else if isOtpValid(tokenHash, user.RecoveryToken, user.RecoverySentAt, recoveryOtpExp(user, &config.Mailer)) {
 			isValid = true
 			params.Type = magicLinkVerification
 		} else {
 			isValid = false
 		}
 	case signupVerification, inviteVerification:
-		isValid = isOtpValid(tokenHash, user.ConfirmationToken, user.ConfirmationSentAt, config.Mailer.OtpExp)
+		isValid = isOtpValid(tokenHash, user.ConfirmationToken, user.ConfirmationSentAt, confirmationOtpExp(user, config.Mailer.OtpExp))
 	case recoveryVerification, magicLinkVerification:
-		isValid = isOtpValid(tokenHash, user.RecoveryToken, user.RecoverySentAt, config.Mailer.OtpExp)
+		isValid = isOtpValid(tokenHash, user.RecoveryToken, user.RecoverySentAt, recoveryOtpExp(user, &config.Mailer))
 	case emailChangeVerification:
 		isValid = isOtpValid(tokenHash, user.EmailChangeTokenCurrent, user.EmailChangeSentAt, config.Mailer.OtpExp) ||
 			isOtpValid(tokenHash, user.EmailChangeTokenNew, user.EmailChangeSentAt, config.Mailer.OtpExp)
@@ -634,6 +664,9 @@ func (a *API) verifyUserAndToken(ctx context.Context, conn *storage.Connection,
 	}
 
 	if !isValid {
+		if isRecoveryTokenVerification(params.Type) && isOtpMatch(tokenHash, user.MagicLinkUsedToken) {
+			return nil, expiredTokenError(magicLinkUsedMsg).WithInternalMessage("magic link has already been used")
+		}
 		return nil, expiredTokenError("Token has expired or is invalid").WithInternalMessage("token has expired or is invalid")
 	}
 	return user, nil
@@ -644,13 +677,45 @@ func isOtpValid(actual, expected string, sentAt *time.Time, otpExp uint) bool {
 	if expected == "" || sentAt == nil {
 		return false
 	}
-	return !isOtpExpired(sentAt, otpExp) && ((actual == expected) || ("pkce_"+actual == expected))
+	return !isOtpExpired(sentAt, otpExp) && isOtpMatch(actual, expected)
+}
+
+func isOtpMatch(actual, expected string) bool {
+	return expected != "" && ((actual == expected) || ("pkce_"+actual == expected))
 }
 
 func isOtpExpired(sentAt *time.Time, otpExp uint) bool {
 	return time.Now().After(sentAt.Add(time.Second * time.Duration(otpExp)))
 }
 
+// confirmationOtpExp returns how long the user's confirmation token is valid
+// for, which is different from the default for invites sent with an expiry.
+func confirmationOtpExp(user *models.User, otpExp uint) uint {
+	if user.InviteExpiresAt == nil || user.ConfirmationSentAt == nil {
+		return otpExp
+	}
+	validFor := user.InviteExpiresAt.Sub(*user.ConfirmationSentAt)
+	if validFor <= 0 {
+		return 0
+	}
+	return uint(validFor / time.Second)
+}
+
+// recoveryOtpExp returns how long the user's recovery token is valid for,
+// which is different from the default when it was sent as a magic link.
+func recoveryOtpExp(user *models.User, config *conf.MailerConfiguration) uint {
+	if user.MagicLinkSentAt != nil {
+		return config.MagicLinkExp
+	}
+	return config.OtpExp
+}
+
+// isRecoveryTokenVerification checks if the verification type can be
+// verified with a recovery token, which is also used for magic links
+func isRecoveryTokenVerification(verificationType string) bool {
+	return verificationType == emailOTPVerification || verificationType == recoveryVerification || verificationType == magicLinkVerification
+}
+
 // isPhoneOtpVerification checks if the verification came from a phone otp
 func isPhoneOtpVerification(params *VerifyParams) bool {
 	return params.Phone != "" && params.Email == ""
@@ -406,6 +406,71 @@ func (ts *VerifyTestSuite) TestExpiredRecoveryToken() {
 	assert.Equal(ts.T(), http.StatusSeeOther, w.Code, w.Body.String())
 }
 
+func (ts *VerifyTestSuite) TestVerifyMagicLinkSingleUse() {
+	u, err := models.FindUserByEmailAndAudience(ts.API.db, "test@example.com", ts.Config.JWT.Aud)
+	require.NoError(ts.T(), err)
+	u.RecoveryToken = crypto.GenerateTokenHash(u.GetEmail(), "123456")
+	sentTime := time.Now()
+	u.RecoverySentAt = &sentTime
+	u.MagicLinkSentAt = &sentTime
+	require.NoError(ts.T(), ts.API.db.Update(u))
+
+	verify := func() *httptest.ResponseRecorder {
+		var buffer bytes.Buffer
+		require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+			"type":  magicLinkVerification,
+			"email": u.GetEmail(),
+			"token": "123456",
+		}))
+		req := httptest.NewRequest(http.MethodPost, "http://localhost/verify", &buffer)
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		ts.API.handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := verify()
+	require.Equal(ts.T(), http.StatusOK, w.Code, w.Body.String())
+
+	// the same magic link can't be used twice
+	w = verify()
+	require.Equal(ts.T(), http.StatusUnauthorized, w.Code)
+	var data HTTPError
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+	assert.Equal(ts.T(), magicLinkUsedMsg, data.Message)
+}
+
+func (ts *VerifyTestSuite) TestExpiredMagicLink() {
+	ts.Config.Mailer.MagicLinkExp = 60
+	defer func() {
+		ts.Config.Mailer.MagicLinkExp = ts.Config.Mailer.OtpExp
+	}()
+
+	u, err := models.FindUserByEmailAndAudience(ts.API.db, "test@example.com", ts.Config.JWT.Aud)
+	require.NoError(ts.T(), err)
+	u.RecoveryToken = crypto.GenerateTokenHash(u.GetEmail(), "123456")
+	sentTime := time.Now().Add(-5 * time.Minute)
+	u.RecoverySentAt = &sentTime
+	u.MagicLinkSentAt = &sentTime
+	require.NoError(ts.T(), ts.API.db.Update(u))
+
+	var buffer bytes.Buffer
+	require.NoError(ts.T(), json.NewEncoder(&buffer).Encode(map[string]interface{}{
+		"type":       magicLinkVerification,
+		"token_hash": u.RecoveryToken,
+	}))
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/verify", &buffer)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	ts.API.handler.ServeHTTP(w, req)
+	require.Equal(ts.T(), http.StatusUnauthorized, w.Code)
+	var data HTTPError
+	require.NoError(ts.T(), json.NewDecoder(w.Body).Decode(&data))
+	assert.Equal(ts.T(), magicLinkExpiredMsg, data.Message)
+}
+
 func (ts *VerifyTestSuite) TestVerifyPermitedCustomUri() {
 	u, err := models.FindUserByEmailAndAudience(ts.API.db, "test@example.com", ts.Config.JWT.Aud)
 	require.NoError(ts.T(), err)
@@ -1059,6 +1124,47 @@ func (ts *VerifyTestSuite) TestPrepRedirectURL() {
 	}
 }
 
+func (ts *VerifyTestSuite) TestRedirectFromEmailLink() {
+	ts.Config.Mailer.RedirectInterstitial = true
+	defer func() {
+		ts.Config.Mailer.RedirectInterstitial = false
+	}()
+
+	cases := []struct {
+		desc             string
+		rurl             string
+		expectedCode     int
+		expectedLocation string
+	}{
+		{
+			desc:             "https URLs are redirected to",
+			rurl:             "https://example.com/welcome#access_token=token",
+			expectedCode:     http.StatusSeeOther,
+			expectedLocation: "https://example.com/welcome#access_token=token",
+		},
+		{
+			desc:         "custom schemes are linked to from an interstitial page",
+			rurl:         "com.example.app://callback#access_token=token",
+			expectedCode: http.StatusOK,
+		},
+	}
+	for _, c := range cases {
+		ts.Run(c.desc, func() {
+			req := httptest.NewRequest(http.MethodGet, "http://localhost/verify", nil)
+			w := httptest.NewRecorder()
+			ts.API.redirectFromEmailLink(w, req, c.rurl)
+
+			require.Equal(ts.T(), c.expectedCode, w.Code)
+			if c.expectedLocation != "" {
+				assert.Equal(ts.T(), c.expectedLocation, w.Header().Get("Location"))
+			} else {
+				assert.Equal(ts.T(), "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+				assert.Contains(ts.T(), w.Body.String(), `href="com.example.app://callback#access_token=token"`)
+			}
+		})
+	}
+}
+
 func (ts *VerifyTestSuite) TestPrepErrorRedirectURL() {
 	const DefaultError = "Invalid redirect URL"
 	redirectError := fmt.Sprintf("error=invalid_request&error_code=400&error_description=%s", url.QueryEscape(DefaultError))
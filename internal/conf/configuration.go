@@ -19,6 +19,10 @@ import (
 const defaultMinPasswordLength int = 6
 const defaultChallengeExpiryDuration float64 = 300
 const defaultFlowStateExpiryDuration time.Duration = 300 * time.Second
+const defaultDeviceCodeExpiryDuration time.Duration = 600 * time.Second
+const defaultDevicePollingInterval time.Duration = 5 * time.Second
+const defaultOAuthServerAuthorizationCodeExpiryDuration time.Duration = 600 * time.Second
+const defaultAPIShutdownTimeout time.Duration = time.Minute
 
 var postgresNamesRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]{0,62}$`)
 
@@ -68,8 +72,13 @@ type DBConfiguration struct {
 	ConnMaxLifetime   time.Duration `json:"conn_max_lifetime,omitempty" split_words:"true"`
 	ConnMaxIdleTime   time.Duration `json:"conn_max_idle_time,omitempty" split_words:"true"`
 	HealthCheckPeriod time.Duration `json:"health_check_period" split_words:"true"`
-	MigrationsPath    string        `json:"migrations_path" split_words:"true" default:"./migrations"`
+	MigrationsPath    string        `json:"migrations_path" split_words:"true"`
 	CleanupEnabled    bool          `json:"cleanup_enabled" split_words:"true" default:"false"`
+
+	// ReplicaURL is a read replica, e.g. in the same region as this
+	// instance, used for reads that can tolerate replication lag. All
+	// writes go to URL.
+	ReplicaURL string `json:"replica_url" split_words:"true"`
 }
 
 func (c *DBConfiguration) Validate() error {
@@ -86,6 +95,13 @@ type JWTConfiguration struct {
 	DefaultGroupName string   `json:"default_group_name" split_words:"true"`
 	Issuer           string   `json:"issuer"`
 	KeyID            string   `json:"key_id" split_words:"true"`
+
+	// PreviousSecret is still accepted when verifying access tokens, so the
+	// secret can be rotated without signing out all users.
+	PreviousSecret string `json:"previous_secret" split_words:"true"`
+	// PreviousSecretExpiresAt is when PreviousSecret stops being accepted,
+	// as an RFC 3339 timestamp. It's accepted indefinitely if it's unset.
+	PreviousSecretExpiresAt *time.Time `json:"previous_secret_expires_at" split_words:"true"`
 }
 
 // MFAConfiguration holds all the MFA related Configuration
@@ -95,6 +111,75 @@ type MFAConfiguration struct {
 	RateLimitChallengeAndVerify float64 `split_words:"true" default:"15"`
 	MaxEnrolledFactors          float64 `split_words:"true" default:"10"`
 	MaxVerifiedFactors          int     `split_words:"true" default:"10"`
+
+	// StepUpMaxAge refuses to refresh sessions of users with verified
+	// factors when the session was last verified with MFA longer ago.
+	StepUpMaxAge time.Duration `json:"step_up_max_age" split_words:"true"`
+	// StepUpRequireAAL2 refuses to refresh aal1 sessions of users with
+	// verified factors, such as sessions created before a factor was
+	// enrolled or downgraded after one was removed.
+	StepUpRequireAAL2 bool `json:"step_up_require_aal2" split_words:"true"`
+}
+
+// DeviceAuthorizationConfiguration holds the configuration for the OAuth 2.0
+// device authorization grant (RFC 8628).
+type DeviceAuthorizationConfiguration struct {
+	Enabled         bool          `json:"enabled" default:"false"`
+	ExpiryDuration  time.Duration `json:"expiry_duration" split_words:"true"`
+	PollingInterval time.Duration `json:"polling_interval" split_words:"true"`
+	// VerificationURL is the page where users enter the user code shown
+	// on the device. Defaults to SITE_URL + /device.
+	VerificationURL string `json:"verification_url" split_words:"true"`
+}
+
+// OAuthServerConfiguration holds the configuration for running as an OAuth
+// 2.0 authorization server for registered third-party clients.
+type OAuthServerConfiguration struct {
+	Enabled                         bool          `json:"enabled" default:"false"`
+	AuthorizationCodeExpiryDuration time.Duration `json:"authorization_code_expiry_duration" split_words:"true"`
+	// ConsentURL is the page where signed in users approve or deny a
+	// client's authorization request. Defaults to SITE_URL + /oauth/consent.
+	ConsentURL string `json:"consent_url" split_words:"true"`
+	// ClientCredentialsDefaultRole is the role claim of access tokens issued
+	// with the client credentials grant to clients without their own role.
+	// It is a dedicated role so that service accounts don't get the access
+	// of signed in users.
+	ClientCredentialsDefaultRole string `json:"client_credentials_default_role" split_words:"true" default:"service_account"`
+}
+
+// SignupApprovalConfiguration holds the configuration for holding new
+// signups until an admin approves them.
+type SignupApprovalConfiguration struct {
+	Enabled bool `json:"enabled" default:"false"`
+	// OperatorEmails are notified of every signup waiting for approval.
+	OperatorEmails []string `json:"operator_emails" split_words:"true"`
+	// NotifyApplicants emails users once their signup has been approved.
+	NotifyApplicants bool `json:"notify_applicants" split_words:"true"`
+}
+
+// ProfileConfiguration holds the configuration for the user metadata fields
+// users need to fill in before they get unrestricted access tokens.
+type ProfileConfiguration struct {
+	RequiredFields []string `json:"required_fields" split_words:"true"`
+	// IncompleteRole is the role claim of the limited access tokens, so
+	// resource servers don't grant them the role of the user.
+	IncompleteRole string `json:"incomplete_role" split_words:"true" default:"incomplete_profile"`
+}
+
+// IsIncomplete reports whether any of the required fields is missing or
+// blank in the user metadata.
+func (c *ProfileConfiguration) IsIncomplete(userMetaData map[string]interface{}) bool {
+	for _, field := range c.RequiredFields {
+		value, ok := userMetaData[field]
+		if !ok || value == nil {
+			return true
+		}
+		if s, ok := value.(string); ok && strings.TrimSpace(s) == "" {
+			return true
+		}
+	}
+
+	return false
 }
 
 type APIConfiguration struct {
@@ -103,6 +188,9 @@ type APIConfiguration struct {
 	Endpoint        string
 	RequestIDHeader string `envconfig:"REQUEST_ID_HEADER"`
 	ExternalURL     string `json:"external_url" envconfig:"API_EXTERNAL_URL" required:"true"`
+	// ShutdownTimeout is how long in-flight requests are given to finish
+	// on shutdown before they're canceled.
+	ShutdownTimeout time.Duration `json:"shutdown_timeout" split_words:"true"`
 }
 
 func (a *APIConfiguration) Validate() error {
@@ -210,16 +298,20 @@ type GlobalConfiguration struct {
 	SiteURL         string   `json:"site_url" split_words:"true" required:"true"`
 	URIAllowList    []string `json:"uri_allow_list" split_words:"true"`
 	URIAllowListMap map[string]glob.Glob
-	Password        PasswordConfiguration    `json:"password"`
-	JWT             JWTConfiguration         `json:"jwt"`
-	Mailer          MailerConfiguration      `json:"mailer"`
-	Sms             SmsProviderConfiguration `json:"sms"`
-	DisableSignup   bool                     `json:"disable_signup" split_words:"true"`
-	Webhook         WebhookConfig            `json:"webhook" split_words:"true"`
-	Hook            HookConfiguration        `json:"hook" split_words:"true"`
-	Security        SecurityConfiguration    `json:"security"`
-	Sessions        SessionsConfiguration    `json:"sessions"`
-	MFA             MFAConfiguration         `json:"MFA"`
+	Password        PasswordConfiguration            `json:"password"`
+	JWT             JWTConfiguration                 `json:"jwt"`
+	Mailer          MailerConfiguration              `json:"mailer"`
+	Sms             SmsProviderConfiguration         `json:"sms"`
+	DisableSignup   bool                             `json:"disable_signup" split_words:"true"`
+	Webhook         WebhookConfig                    `json:"webhook" split_words:"true"`
+	Hook            HookConfiguration                `json:"hook" split_words:"true"`
+	Security        SecurityConfiguration            `json:"security"`
+	Sessions        SessionsConfiguration            `json:"sessions"`
+	MFA             MFAConfiguration                 `json:"MFA"`
+	Device          DeviceAuthorizationConfiguration `json:"device"`
+	OAuthServer     OAuthServerConfiguration         `json:"oauth_server" split_words:"true"`
+	SignupApproval  SignupApprovalConfiguration      `json:"signup_approval" split_words:"true"`
+	Profile         ProfileConfiguration             `json:"profile"`
 	Cookie          struct {
 		Key      string `json:"key"`
 		Domain   string `json:"domain"`
@@ -261,6 +353,9 @@ type EmailContentConfiguration struct {
 	EmailChange      string `json:"email_change" split_words:"true"`
 	MagicLink        string `json:"magic_link" split_words:"true"`
 	Reauthentication string `json:"reauthentication"`
+
+	SignupApprovalRequested string `json:"signup_approval_requested" split_words:"true"`
+	SignupApproved          string `json:"signup_approved" split_words:"true"`
 }
 
 type ProviderConfiguration struct {
@@ -311,6 +406,11 @@ type MailerConfiguration struct {
 	Autoconfirm                 bool `json:"autoconfirm"`
 	AllowUnverifiedEmailSignIns bool `json:"allow_unverified_email_sign_ins" split_words:"true" default:"false"`
 
+	// AutoconfirmDomains and RequireConfirmationDomains override
+	// Autoconfirm for emails on those domains.
+	AutoconfirmDomains         []string `json:"autoconfirm_domains" split_words:"true"`
+	RequireConfirmationDomains []string `json:"require_confirmation_domains" split_words:"true"`
+
 	Subjects  EmailContentConfiguration `json:"subjects"`
 	Templates EmailContentConfiguration `json:"templates"`
 	URLPaths  EmailContentConfiguration `json:"url_paths"`
@@ -319,6 +419,37 @@ type MailerConfiguration struct {
 
 	OtpExp    uint `json:"otp_exp" split_words:"true"`
 	OtpLength int  `json:"otp_length" split_words:"true"`
+
+	// MagicLinkExp is how long a magic link is valid for, defaults to OtpExp
+	MagicLinkExp uint `json:"magic_link_exp" split_words:"true"`
+
+	// RedirectInterstitial shows a page linking to the app instead of
+	// redirecting directly when an email link redirects to a non-https URL,
+	// as some email clients block those redirects.
+	RedirectInterstitial bool `json:"redirect_interstitial" split_words:"true"`
+}
+
+// ShouldAutoconfirm reports whether a signup with the email should be
+// confirmed without sending a confirmation email. Domains requiring
+// confirmation take precedence over autoconfirmed domains.
+func (c *MailerConfiguration) ShouldAutoconfirm(email string) bool {
+	domain := email
+	if i := strings.LastIndex(email, "@"); i >= 0 {
+		domain = email[i+1:]
+	}
+
+	for _, d := range c.RequireConfirmationDomains {
+		if strings.EqualFold(d, domain) {
+			return false
+		}
+	}
+	for _, d := range c.AutoconfirmDomains {
+		if strings.EqualFold(d, domain) {
+			return true
+		}
+	}
+
+	return c.Autoconfirm
 }
 
 type PhoneProviderConfiguration struct {
@@ -514,6 +645,19 @@ func (w *WebhookConfig) HasEvent(event string) bool {
 	return false
 }
 
+// ReloadGlobal loads the configuration again, the same as LoadGlobal except
+// that the variables in .env override the environment too when filename is
+// empty. The environment of the process still has the values of .env loaded
+// at startup, so they'd shadow the changes otherwise.
+func ReloadGlobal(filename string) (*GlobalConfiguration, error) {
+	if filename == "" {
+		if _, err := os.Stat(".env"); err == nil {
+			filename = ".env"
+		}
+	}
+	return LoadGlobal(filename)
+}
+
 func LoadGlobal(filename string) (*GlobalConfiguration, error) {
 	if err := loadEnvironment(filename); err != nil {
 		return nil, err
@@ -577,6 +721,10 @@ func LoadGlobal(filename string) (*GlobalConfiguration, error) {
 
 // ApplyDefaults sets defaults for a GlobalConfiguration
 func (config *GlobalConfiguration) ApplyDefaults() error {
+	if config.API.ShutdownTimeout <= 0 {
+		config.API.ShutdownTimeout = defaultAPIShutdownTimeout
+	}
+
 	if config.JWT.AdminGroupName == "" {
 		config.JWT.AdminGroupName = "admin"
 	}
@@ -593,6 +741,10 @@ func (config *GlobalConfiguration) ApplyDefaults() error {
 		return errors.New("cannot enable both GOTRUE_MAILER_AUTOCONFIRM and GOTRUE_MAILER_ALLOW_UNVERIFIED_EMAIL_SIGN_INS")
 	}
 
+	if len(config.Mailer.AutoconfirmDomains) > 0 && config.Mailer.AllowUnverifiedEmailSignIns {
+		return errors.New("cannot set both GOTRUE_MAILER_AUTOCONFIRM_DOMAINS and GOTRUE_MAILER_ALLOW_UNVERIFIED_EMAIL_SIGN_INS")
+	}
+
 	if config.Mailer.URLPaths.Invite == "" {
 		config.Mailer.URLPaths.Invite = "/verify"
 	}
@@ -613,6 +765,10 @@ func (config *GlobalConfiguration) ApplyDefaults() error {
 		config.Mailer.OtpExp = 86400 // 1 day
 	}
 
+	if config.Mailer.MagicLinkExp == 0 {
+		config.Mailer.MagicLinkExp = config.Mailer.OtpExp
+	}
+
 	if config.Mailer.OtpLength == 0 || config.Mailer.OtpLength < 6 || config.Mailer.OtpLength > 10 {
 		// 6-digit otp by default
 		config.Mailer.OtpLength = 6
@@ -658,7 +814,10 @@ func (config *GlobalConfiguration) ApplyDefaults() error {
 	if config.URIAllowList != nil {
 		config.URIAllowListMap = make(map[string]glob.Glob)
 		for _, uri := range config.URIAllowList {
-			g := glob.MustCompile(uri, '.', '/')
+			g, err := glob.Compile(uri, '.', '/')
+			if err != nil {
+				return fmt.Errorf("invalid URI allow list entry %q: %w", uri, err)
+			}
 			config.URIAllowListMap[uri] = g
 		}
 	}
@@ -673,6 +832,23 @@ func (config *GlobalConfiguration) ApplyDefaults() error {
 		config.External.FlowStateExpiryDuration = defaultFlowStateExpiryDuration
 	}
 
+	if config.Device.ExpiryDuration <= 0 {
+		config.Device.ExpiryDuration = defaultDeviceCodeExpiryDuration
+	}
+	if config.Device.PollingInterval <= 0 {
+		config.Device.PollingInterval = defaultDevicePollingInterval
+	}
+	if config.Device.VerificationURL == "" {
+		config.Device.VerificationURL = strings.TrimSuffix(config.SiteURL, "/") + "/device"
+	}
+
+	if config.OAuthServer.AuthorizationCodeExpiryDuration <= 0 {
+		config.OAuthServer.AuthorizationCodeExpiryDuration = defaultOAuthServerAuthorizationCodeExpiryDuration
+	}
+	if config.OAuthServer.ConsentURL == "" {
+		config.OAuthServer.ConsentURL = strings.TrimSuffix(config.SiteURL, "/") + "/oauth/consent"
+	}
+
 	if len(config.External.AllowedIdTokenIssuers) == 0 {
 		config.External.AllowedIdTokenIssuers = append(config.External.AllowedIdTokenIssuers, "https://appleid.apple.com", "https://accounts.google.com")
 	}
@@ -2,6 +2,7 @@ package conf
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -29,6 +30,39 @@ func TestGlobal(t *testing.T) {
 	assert.Equal(t, "pg-functions://postgres/auth/count_failed_attempts", gc.Hook.MFAVerificationAttempt.URI)
 }
 
+func TestReloadGlobalOverridesEnvironment(t *testing.T) {
+	t.Setenv("GOTRUE_SITE_URL", "http://localhost:8080")
+	t.Setenv("GOTRUE_DB_DRIVER", "postgres")
+	t.Setenv("GOTRUE_DB_DATABASE_URL", "fake")
+	t.Setenv("GOTRUE_JWT_SECRET", "secret")
+	t.Setenv("API_EXTERNAL_URL", "http://localhost:9999")
+	t.Setenv("GOTRUE_MAILER_SUBJECTS_INVITE", "Loaded at startup")
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".env"), []byte("GOTRUE_MAILER_SUBJECTS_INVITE=\"Changed since\"\n"), 0600))
+	require.NoError(t, os.Chdir(dir))
+	defer func() {
+		require.NoError(t, os.Chdir(wd))
+	}()
+
+	gc, err := LoadGlobal("")
+	require.NoError(t, err)
+	assert.Equal(t, "Loaded at startup", gc.Mailer.Subjects.Invite)
+
+	gc, err = ReloadGlobal("")
+	require.NoError(t, err)
+	assert.Equal(t, "Changed since", gc.Mailer.Subjects.Invite)
+}
+
+func TestInvalidURIAllowList(t *testing.T) {
+	config := &GlobalConfiguration{URIAllowList: []string{"http://localhost:3000/**", "http://["}}
+	err := config.ApplyDefaults()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid URI allow list entry "http://["`)
+}
+
 func TestPasswordRequiredCharactersDecode(t *testing.T) {
 	examples := []struct {
 		Value  string
@@ -125,3 +159,33 @@ func TestValidateExtensibilityPoint(t *testing.T) {
 		}
 	}
 }
+
+func TestMailerShouldAutoconfirm(t *testing.T) {
+	c := &MailerConfiguration{
+		AutoconfirmDomains:         []string{"example.com"},
+		RequireConfirmationDomains: []string{"untrusted.example.com"},
+	}
+
+	assert.True(t, c.ShouldAutoconfirm("user@example.com"))
+	assert.True(t, c.ShouldAutoconfirm("user@EXAMPLE.com"))
+	assert.False(t, c.ShouldAutoconfirm("user@sub.example.com"))
+	assert.False(t, c.ShouldAutoconfirm("user@untrusted.example.com"))
+	assert.False(t, c.ShouldAutoconfirm("user@example.org"))
+
+	c.Autoconfirm = true
+	assert.True(t, c.ShouldAutoconfirm("user@example.org"))
+	assert.False(t, c.ShouldAutoconfirm("user@untrusted.example.com"))
+	assert.True(t, c.ShouldAutoconfirm(""))
+}
+
+func TestProfileIsIncomplete(t *testing.T) {
+	c := &ProfileConfiguration{}
+	assert.False(t, c.IsIncomplete(nil))
+
+	c.RequiredFields = []string{"display_name"}
+	assert.True(t, c.IsIncomplete(nil))
+	assert.True(t, c.IsIncomplete(map[string]interface{}{"display_name": nil}))
+	assert.True(t, c.IsIncomplete(map[string]interface{}{"display_name": " "}))
+	assert.False(t, c.IsIncomplete(map[string]interface{}{"display_name": "Test"}))
+	assert.False(t, c.IsIncomplete(map[string]interface{}{"display_name": 42}))
+}
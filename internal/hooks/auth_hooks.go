@@ -81,6 +81,9 @@ const MinimumViableTokenSchema = `{
     },
     "session_id": {
       "type": "string"
+    },
+    "scope": {
+      "type": "string"
     }
   },
   "required": ["aud", "exp", "iat", "sub", "email", "phone", "role", "aal"]
@@ -97,6 +100,8 @@ type AccessTokenClaims struct {
 	AuthenticatorAssuranceLevel   string                 `json:"aal,omitempty"`
 	AuthenticationMethodReference []models.AMREntry      `json:"amr,omitempty"`
 	SessionId                     string                 `json:"session_id,omitempty"`
+	Scope                         string                 `json:"scope,omitempty"`
+	ClientID                      string                 `json:"client_id,omitempty"`
 }
 
 type MFAVerificationAttemptInput struct {
@@ -21,6 +21,8 @@ type Mailer interface {
 	MagicLinkMail(user *models.User, otp, referrerURL string, externalURL *url.URL) error
 	EmailChangeMail(user *models.User, otpNew, otpCurrent, referrerURL string, externalURL *url.URL) error
 	ReauthenticateMail(user *models.User, otp string) error
+	SignupApprovalRequestedMail(user *models.User, operatorEmail string) error
+	SignupApprovedMail(user *models.User) error
 	ValidateEmail(email string) error
 	GetEmailActionLink(user *models.User, actionType, referrerURL string, externalURL *url.URL) (string, error)
 }
@@ -68,6 +68,14 @@ const defaultReauthenticateMail = `<h2>Confirm reauthentication</h2>
 
 <p>Enter the code: {{ .Token }}</p>`
 
+const defaultSignupApprovalRequestedMail = `<h2>New signup waiting for approval</h2>
+
+<p>{{ .Email }} signed up on {{ .SiteURL }} and is waiting for approval.</p>`
+
+const defaultSignupApprovedMail = `<h2>Your signup has been approved</h2>
+
+<p>Your account on {{ .SiteURL }} has been approved. You can now log in.</p>`
+
 // ValidateEmail returns nil if the email is valid,
 // otherwise an error indicating the reason it is invalid
 func (m TemplateMailer) ValidateEmail(email string) error {
@@ -153,6 +161,42 @@ func (m *TemplateMailer) ReauthenticateMail(user *models.User, otp string) error
 	)
 }
 
+// SignupApprovalRequestedMail notifies an operator of a signup waiting for
+// approval
+func (m *TemplateMailer) SignupApprovalRequestedMail(user *models.User, operatorEmail string) error {
+	data := map[string]interface{}{
+		"SiteURL": m.Config.SiteURL,
+		"Email":   user.GetEmail(),
+		"Phone":   user.GetPhone(),
+		"Data":    user.UserMetaData,
+	}
+
+	return m.Mailer.Mail(
+		operatorEmail,
+		withDefault(m.Config.Mailer.Subjects.SignupApprovalRequested, "New signup waiting for approval"),
+		m.Config.Mailer.Templates.SignupApprovalRequested,
+		defaultSignupApprovalRequestedMail,
+		data,
+	)
+}
+
+// SignupApprovedMail tells a user that their signup has been approved
+func (m *TemplateMailer) SignupApprovedMail(user *models.User) error {
+	data := map[string]interface{}{
+		"SiteURL": m.Config.SiteURL,
+		"Email":   user.Email,
+		"Data":    user.UserMetaData,
+	}
+
+	return m.Mailer.Mail(
+		user.GetEmail(),
+		withDefault(m.Config.Mailer.Subjects.SignupApproved, "Your signup has been approved"),
+		m.Config.Mailer.Templates.SignupApproved,
+		defaultSignupApprovedMail,
+		data,
+	)
+}
+
 // EmailChangeMail sends an email change confirmation mail to a user
 func (m *TemplateMailer) EmailChangeMail(user *models.User, otpNew, otpCurrent, referrerURL string, externalURL *url.URL) error {
 	type Email struct {
@@ -22,6 +22,8 @@ const (
 	InviteAcceptedAction            AuditAction = "invite_accepted"
 	UserSignedUpAction              AuditAction = "user_signedup"
 	UserInvitedAction               AuditAction = "user_invited"
+	UserInviteRevokedAction         AuditAction = "user_invite_revoked"
+	UserApprovedAction              AuditAction = "user_approved"
 	UserDeletedAction               AuditAction = "user_deleted"
 	UserModifiedAction              AuditAction = "user_modified"
 	UserRecoveryRequestedAction     AuditAction = "user_recovery_requested"
@@ -41,6 +43,11 @@ const (
 	UpdateFactorAction              AuditAction = "factor_updated"
 	MFACodeLoginAction              AuditAction = "mfa_code_login"
 	IdentityUnlinkAction            AuditAction = "identity_unlinked"
+	DeviceAuthorizedAction          AuditAction = "device_authorized"
+	DeviceDeniedAction              AuditAction = "device_denied"
+	OAuthClientAuthorizedAction     AuditAction = "oauth_client_authorized"
+	OAuthClientDeniedAction         AuditAction = "oauth_client_denied"
+	ClientCredentialsGrantedAction  AuditAction = "client_credentials_granted"
 
 	account       auditLogType = "account"
 	team          auditLogType = "team"
@@ -48,14 +55,21 @@ const (
 	user          auditLogType = "user"
 	factor        auditLogType = "factor"
 	recoveryCodes auditLogType = "recovery_codes"
+	client        auditLogType = "client"
 )
 
 var ActionLogTypeMap = map[AuditAction]auditLogType{
 	LoginAction:                     account,
 	LogoutAction:                    account,
 	InviteAcceptedAction:            account,
+	DeviceAuthorizedAction:          account,
+	DeviceDeniedAction:              account,
+	OAuthClientAuthorizedAction:     account,
+	OAuthClientDeniedAction:         account,
 	UserSignedUpAction:              team,
 	UserInvitedAction:               team,
+	UserInviteRevokedAction:         team,
+	UserApprovedAction:              team,
 	UserDeletedAction:               team,
 	TokenRevokedAction:              token,
 	TokenRefreshedAction:            token,
@@ -73,6 +87,7 @@ var ActionLogTypeMap = map[AuditAction]auditLogType{
 	UpdateFactorAction:              factor,
 	MFACodeLoginAction:              factor,
 	DeleteRecoveryCodesAction:       recoveryCodes,
+	ClientCredentialsGrantedAction:  client,
 }
 
 // AuditLogEntry is the database model for audit log entries.
@@ -131,6 +146,38 @@ func NewAuditLogEntry(r *http.Request, tx *storage.Connection, actor *User, acti
 	return nil
 }
 
+// NewClientAuditLogEntry records an action taken by an OAuth client on its
+// own behalf, such as obtaining a token with the client credentials grant,
+// where there is no user to attribute the action to.
+func NewClientAuditLogEntry(r *http.Request, tx *storage.Connection, actor *OAuthClient, action AuditAction, ipAddress string, traits map[string]interface{}) error {
+	payload := map[string]interface{}{
+		"actor_id":       actor.ID,
+		"actor_type":     "oauth_client",
+		"actor_username": actor.Name,
+		"action":         action,
+		"log_type":       ActionLogTypeMap[action],
+	}
+	l := AuditLogEntry{
+		ID:        uuid.Must(uuid.NewV4()),
+		Payload:   JSONMap(payload),
+		IPAddress: ipAddress,
+	}
+
+	observability.LogEntrySetFields(r, logrus.Fields{
+		"auth_event": logrus.Fields(payload),
+	})
+
+	if traits != nil {
+		l.Payload["traits"] = traits
+	}
+
+	if err := tx.Create(&l); err != nil {
+		return errors.Wrap(err, "Database error creating audit log entry")
+	}
+
+	return nil
+}
+
 func FindAuditLogEntries(tx *storage.Connection, filterColumns []string, filterValue string, pageParams *Pagination) ([]*AuditLogEntry, error) {
 	q := tx.Q().Order("created_at desc").Where("instance_id = ?", uuid.Nil)
 
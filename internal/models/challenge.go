@@ -5,6 +5,7 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
 	"github.com/supabase/auth/internal/storage"
+	"strconv"
 	"time"
 )
 
@@ -33,6 +34,20 @@ func NewChallenge(factor *Factor, ipAddress string) (*Challenge, error) {
 	return challenge, nil
 }
 
+// NewStepUpChallenge records a TOTP code verified with the refresh token
+// grant, which has no challenge of its own. The ID is derived from the
+// factor and the time step of the code, so that a code can only be used
+// once.
+func NewStepUpChallenge(factor *Factor, timeStep uint64, ipAddress string) *Challenge {
+	now := time.Now()
+	return &Challenge{
+		ID:         uuid.NewV5(factor.ID, "step_up:"+strconv.FormatUint(timeStep, 10)),
+		FactorID:   factor.ID,
+		VerifiedAt: &now,
+		IPAddress:  ipAddress,
+	}
+}
+
 func FindChallengeByChallengeID(tx *storage.Connection, challengeID uuid.UUID) (*Challenge, error) {
 	challenge, err := findChallenge(tx, "id = ?", challengeID)
 	if err != nil {
@@ -48,6 +48,9 @@ func TruncateAll(conn *storage.Connection) error {
 			(&pop.Model{Value: SAMLProvider{}}).TableName(),
 			(&pop.Model{Value: SAMLRelayState{}}).TableName(),
 			(&pop.Model{Value: FlowState{}}).TableName(),
+			(&pop.Model{Value: DeviceCode{}}).TableName(),
+			(&pop.Model{Value: OAuthAuthorization{}}).TableName(),
+			(&pop.Model{Value: OAuthClient{}}).TableName(),
 		}
 
 		for _, tableName := range tables {
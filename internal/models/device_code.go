@@ -0,0 +1,200 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/supabase/auth/internal/crypto"
+	"github.com/supabase/auth/internal/storage"
+)
+
+// userCodeCharset excludes vowels and easily confused characters, as
+// recommended by RFC 8628 Section 6.1.
+const userCodeCharset = "BCDFGHJKLMNPQRSTVWXZ"
+const userCodeLength = 8
+
+// slowDownIncrement is added to the polling interval of a device that polls
+// too frequently, see RFC 8628 Section 3.5.
+const slowDownIncrement = 5 * time.Second
+
+type DeviceCodeStatus int
+
+const (
+	DeviceCodePending DeviceCodeStatus = iota
+	DeviceCodeApproved
+	DeviceCodeDenied
+)
+
+func (status DeviceCodeStatus) String() string {
+	switch status {
+	case DeviceCodePending:
+		return "pending"
+	case DeviceCodeApproved:
+		return "approved"
+	case DeviceCodeDenied:
+		return "denied"
+	}
+	return ""
+}
+
+// DeviceCode is a device authorization request as defined in RFC 8628.
+type DeviceCode struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	ClientID       uuid.UUID  `json:"client_id" db:"client_id"`
+	UserID         *uuid.UUID `json:"user_id,omitempty" db:"user_id"`
+	DeviceCodeHash string     `json:"-" db:"device_code_hash"`
+	UserCode       string     `json:"user_code" db:"user_code"`
+	Status         string     `json:"status" db:"status"`
+	// PollingInterval is the number of seconds the device must wait
+	// between polls.
+	PollingInterval int        `json:"polling_interval" db:"polling_interval"`
+	LastPolledAt    *time.Time `json:"last_polled_at,omitempty" db:"last_polled_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+func (DeviceCode) TableName() string {
+	tableName := "device_codes"
+	return tableName
+}
+
+// NewDeviceCode creates a new device authorization request of the client and
+// returns the generated device code; only its hash is stored, as it is a
+// bearer secret.
+func NewDeviceCode(clientID uuid.UUID, pollingInterval time.Duration) (*DeviceCode, string, error) {
+	userCode, err := generateUserCode()
+	if err != nil {
+		return nil, "", err
+	}
+	code := crypto.SecureToken(32)
+	deviceCode := &DeviceCode{
+		ID:              uuid.Must(uuid.NewV4()),
+		ClientID:        clientID,
+		DeviceCodeHash:  hashDeviceCode(code),
+		UserCode:        userCode,
+		Status:          DeviceCodePending.String(),
+		PollingInterval: int(pollingInterval.Seconds()),
+	}
+	return deviceCode, code, nil
+}
+
+// device codes are long random strings so a plain SHA-256 hash is
+// sufficient
+func hashDeviceCode(deviceCode string) string {
+	sum := sha256.Sum256([]byte(deviceCode))
+	return hex.EncodeToString(sum[:])
+}
+
+// NormalizeUserCode converts a user code as typed in by a user into the
+// canonical XXXX-XXXX form, ignoring case, whitespace and separators.
+func NormalizeUserCode(userCode string) string {
+	var b strings.Builder
+	for _, c := range strings.ToUpper(userCode) {
+		if strings.ContainsRune(userCodeCharset, c) {
+			b.WriteRune(c)
+		}
+	}
+	normalized := b.String()
+	if len(normalized) != userCodeLength {
+		return normalized
+	}
+	return normalized[:userCodeLength/2] + "-" + normalized[userCodeLength/2:]
+}
+
+func generateUserCode() (string, error) {
+	code := make([]byte, userCodeLength)
+	max := big.NewInt(int64(len(userCodeCharset)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", errors.Wrap(err, "error generating user code")
+		}
+		code[i] = userCodeCharset[n.Int64()]
+	}
+	return NormalizeUserCode(string(code)), nil
+}
+
+func FindDeviceCodeByDeviceCode(tx *storage.Connection, deviceCode string) (*DeviceCode, error) {
+	return findDeviceCode(tx, "device_code_hash = ?", hashDeviceCode(deviceCode))
+}
+
+func FindDeviceCodeByUserCode(tx *storage.Connection, userCode string) (*DeviceCode, error) {
+	return findDeviceCode(tx, "user_code = ?", NormalizeUserCode(userCode))
+}
+
+func findDeviceCode(tx *storage.Connection, query string, args ...interface{}) (*DeviceCode, error) {
+	obj := &DeviceCode{}
+	if err := tx.Q().Where(query, args...).First(obj); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, DeviceCodeNotFoundError{}
+		}
+		return nil, errors.Wrap(err, "error finding device code")
+	}
+
+	return obj, nil
+}
+
+// Approve associates the device code with the user who entered the user code.
+func (d *DeviceCode) Approve(tx *storage.Connection, userID uuid.UUID) error {
+	d.UserID = &userID
+	d.Status = DeviceCodeApproved.String()
+	return tx.UpdateOnly(d, "user_id", "status")
+}
+
+func (d *DeviceCode) Deny(tx *storage.Connection) error {
+	d.Status = DeviceCodeDenied.String()
+	return tx.UpdateOnly(d, "status")
+}
+
+// Redeem removes the device code once it has been exchanged or denied. It
+// fails if a concurrent request already removed it, so that a device code
+// can only be redeemed once.
+func (d *DeviceCode) Redeem(tx *storage.Connection) error {
+	count, err := tx.RawQuery("DELETE FROM "+d.TableName()+" WHERE id = ?", d.ID).ExecWithCount()
+	if err != nil {
+		return errors.Wrap(err, "error removing device code")
+	}
+	if count != 1 {
+		return DeviceCodeNotFoundError{}
+	}
+	return nil
+}
+
+// UpdateLastPolledAt records a poll on the token endpoint, used to enforce
+// the polling interval.
+func (d *DeviceCode) UpdateLastPolledAt(tx *storage.Connection) error {
+	now := time.Now()
+	d.LastPolledAt = &now
+	return tx.UpdateOnly(d, "last_polled_at")
+}
+
+// SlowDown records a poll that came too early and increases the polling
+// interval, which the device is expected to adopt.
+func (d *DeviceCode) SlowDown(tx *storage.Connection) error {
+	now := time.Now()
+	d.LastPolledAt = &now
+	d.PollingInterval += int(slowDownIncrement.Seconds())
+	return tx.UpdateOnly(d, "last_polled_at", "polling_interval")
+}
+
+func (d *DeviceCode) IsPending() bool {
+	return d.Status == DeviceCodePending.String()
+}
+
+func (d *DeviceCode) IsExpired(expiryDuration time.Duration) bool {
+	return time.Now().After(d.CreatedAt.Add(expiryDuration))
+}
+
+// IsPolledTooFrequently reports whether the device polled again before the
+// polling interval elapsed.
+func (d *DeviceCode) IsPolledTooFrequently() bool {
+	interval := time.Duration(d.PollingInterval) * time.Second
+	return d.LastPolledAt != nil && time.Now().Before(d.LastPolledAt.Add(interval))
+}
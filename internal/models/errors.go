@@ -23,6 +23,12 @@ func IsNotFoundError(err error) bool {
 		return true
 	case FlowStateNotFoundError, *FlowStateNotFoundError:
 		return true
+	case DeviceCodeNotFoundError, *DeviceCodeNotFoundError:
+		return true
+	case OAuthClientNotFoundError, *OAuthClientNotFoundError:
+		return true
+	case OAuthAuthorizationNotFoundError, *OAuthAuthorizationNotFoundError:
+		return true
 	}
 	return false
 }
@@ -47,6 +53,13 @@ func (e IdentityNotFoundError) Error() string {
 	return "Identity not found"
 }
 
+// RecoveryTokenUsedError represents when a recovery token was already used.
+type RecoveryTokenUsedError struct{}
+
+func (e RecoveryTokenUsedError) Error() string {
+	return "Recovery token has already been used"
+}
+
 // ConfirmationOrRecoveryTokenNotFoundError represents when a confirmation or recovery token is not found.
 type ConfirmationOrRecoveryTokenNotFoundError struct{}
 
@@ -106,6 +119,28 @@ func (e FlowStateNotFoundError) Error() string {
 	return "Flow State not found"
 }
 
+// DeviceCodeNotFoundError represents an error when a device code can't be
+// found.
+type DeviceCodeNotFoundError struct{}
+
+func (e DeviceCodeNotFoundError) Error() string {
+	return "Device code not found"
+}
+
+// OAuthClientNotFoundError represents an error when an OAuth client can't be found.
+type OAuthClientNotFoundError struct{}
+
+func (e OAuthClientNotFoundError) Error() string {
+	return "OAuth client not found"
+}
+
+// OAuthAuthorizationNotFoundError represents an error when an OAuth authorization request can't be found.
+type OAuthAuthorizationNotFoundError struct{}
+
+func (e OAuthAuthorizationNotFoundError) Error() string {
+	return "OAuth authorization not found"
+}
+
 func IsUniqueConstraintViolatedError(err error) bool {
 	switch err.(type) {
 	case UserEmailUniqueConflictError, *UserEmailUniqueConflictError:
@@ -45,6 +45,9 @@ const (
 	EmailSignup
 	EmailChange
 	TokenRefresh
+	DeviceCodeGrant
+	OAuthAuthorizationCode
+	CustomGrant
 )
 
 func (authMethod AuthenticationMethod) String() string {
@@ -71,6 +74,12 @@ func (authMethod AuthenticationMethod) String() string {
 		return "email_change"
 	case TokenRefresh:
 		return "token_refresh"
+	case DeviceCodeGrant:
+		return "device_code"
+	case OAuthAuthorizationCode:
+		return "oauth_provider/authorization_code"
+	case CustomGrant:
+		return "custom_grant"
 	}
 	return ""
 }
@@ -102,6 +111,12 @@ func ParseAuthenticationMethod(authMethod string) (AuthenticationMethod, error)
 		return EmailChange, nil
 	case "token_refresh":
 		return TokenRefresh, nil
+	case "device_code":
+		return DeviceCodeGrant, nil
+	case "oauth_provider/authorization_code":
+		return OAuthAuthorizationCode, nil
+	case "custom_grant":
+		return CustomGrant, nil
 	}
 	return 0, fmt.Errorf("unsupported authentication method %q", authMethod)
 }
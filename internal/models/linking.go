@@ -54,7 +54,7 @@ func DetermineAccountLinking(tx *storage.Connection, config *conf.GlobalConfigur
 	var verifiedEmails []string
 	var candidateEmail provider.Email
 	for _, email := range emails {
-		if email.Verified || config.Mailer.Autoconfirm {
+		if email.Verified || config.Mailer.ShouldAutoconfirm(email.Email) {
 			verifiedEmails = append(verifiedEmails, strings.ToLower(email.Email))
 		}
 		if email.Primary {
@@ -0,0 +1,321 @@
+package models
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/supabase/auth/internal/crypto"
+	"github.com/supabase/auth/internal/storage"
+)
+
+type OAuthClientType int
+
+const (
+	OAuthClientPublic OAuthClientType = iota
+	OAuthClientConfidential
+)
+
+func (clientType OAuthClientType) String() string {
+	switch clientType {
+	case OAuthClientPublic:
+		return "public"
+	case OAuthClientConfidential:
+		return "confidential"
+	}
+	return ""
+}
+
+type OAuthAuthorizationStatus int
+
+const (
+	OAuthAuthorizationPending OAuthAuthorizationStatus = iota
+	OAuthAuthorizationApproved
+)
+
+func (status OAuthAuthorizationStatus) String() string {
+	switch status {
+	case OAuthAuthorizationPending:
+		return "pending"
+	case OAuthAuthorizationApproved:
+		return "approved"
+	}
+	return ""
+}
+
+// OAuthRedirectURIs is the list of redirect URIs registered for an OAuth
+// client, stored as a JSON array.
+type OAuthRedirectURIs []string
+
+func (u *OAuthRedirectURIs) Scan(src interface{}) error {
+	b, ok := src.([]byte)
+	if !ok {
+		return errors.New("scan source was not []byte")
+	}
+	return json.Unmarshal(b, u)
+}
+
+func (u OAuthRedirectURIs) Value() (driver.Value, error) {
+	if u == nil {
+		u = OAuthRedirectURIs{}
+	}
+	b, err := json.Marshal(u)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// OAuthClient is a third-party application registered with the OAuth 2.0
+// authorization server.
+type OAuthClient struct {
+	ID               uuid.UUID         `json:"client_id" db:"id"`
+	Name             string            `json:"name" db:"name"`
+	ClientType       string            `json:"client_type" db:"client_type"`
+	ClientSecretHash *string           `json:"-" db:"client_secret_hash"`
+	RedirectURIs     OAuthRedirectURIs `json:"redirect_uris" db:"redirect_uris"`
+	CreatedAt        time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at" db:"updated_at"`
+
+	// ClientCredentialsEnabled allows a confidential client to obtain
+	// access tokens for itself, with Role and Claims, using the client
+	// credentials grant.
+	ClientCredentialsEnabled bool    `json:"client_credentials_enabled" db:"client_credentials_enabled"`
+	Role                     *string `json:"role,omitempty" db:"role"`
+	Claims                   JSONMap `json:"claims,omitempty" db:"claims"`
+}
+
+func (OAuthClient) TableName() string {
+	tableName := "oauth_clients"
+	return tableName
+}
+
+// NewOAuthClient creates a new client. For confidential clients the
+// generated client secret is returned; only its hash is stored, so it can't
+// be retrieved again later.
+func NewOAuthClient(name string, clientType OAuthClientType, redirectURIs []string) (*OAuthClient, string) {
+	client := &OAuthClient{
+		ID:           uuid.Must(uuid.NewV4()),
+		Name:         name,
+		ClientType:   clientType.String(),
+		RedirectURIs: redirectURIs,
+	}
+
+	var clientSecret string
+	if clientType == OAuthClientConfidential {
+		clientSecret = crypto.SecureToken(32)
+		hashedSecret := hashClientSecret(clientSecret)
+		client.ClientSecretHash = &hashedSecret
+	}
+
+	return client, clientSecret
+}
+
+// client secrets are long random strings so a plain SHA-256 hash is
+// sufficient, unlike for passwords
+func hashClientSecret(clientSecret string) string {
+	sum := sha256.Sum256([]byte(clientSecret))
+	return hex.EncodeToString(sum[:])
+}
+
+func FindOAuthClientByID(tx *storage.Connection, id uuid.UUID) (*OAuthClient, error) {
+	obj := &OAuthClient{}
+	if err := tx.Q().Where("id = ?", id).First(obj); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, OAuthClientNotFoundError{}
+		}
+		return nil, errors.Wrap(err, "error finding oauth client")
+	}
+
+	return obj, nil
+}
+
+func FindAllOAuthClients(tx *storage.Connection) ([]OAuthClient, error) {
+	clients := []OAuthClient{}
+	if err := tx.Q().Order("created_at asc").All(&clients); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return clients, nil
+		}
+		return nil, errors.Wrap(err, "error finding oauth clients")
+	}
+
+	return clients, nil
+}
+
+func (c *OAuthClient) IsConfidential() bool {
+	return c.ClientType == OAuthClientConfidential.String()
+}
+
+// VerifyClientSecret reports whether the secret matches the one generated
+// for a confidential client.
+func (c *OAuthClient) VerifyClientSecret(clientSecret string) bool {
+	if c.ClientSecretHash == nil || clientSecret == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(*c.ClientSecretHash), []byte(hashClientSecret(clientSecret))) == 1
+}
+
+// HasRedirectURI reports whether the redirect URI exactly matches one of
+// the URIs registered for the client.
+func (c *OAuthClient) HasRedirectURI(redirectURI string) bool {
+	for _, uri := range c.RedirectURIs {
+		if uri == redirectURI {
+			return true
+		}
+	}
+	return false
+}
+
+// OAuthAuthorization is an authorization request made by an OAuth client.
+// Once the user consents it holds the authorization code that the client
+// exchanges for tokens.
+type OAuthAuthorization struct {
+	ID                  uuid.UUID  `json:"id" db:"id"`
+	ClientID            uuid.UUID  `json:"client_id" db:"client_id"`
+	UserID              *uuid.UUID `json:"user_id,omitempty" db:"user_id"`
+	RedirectURI         string     `json:"redirect_uri" db:"redirect_uri"`
+	Scope               string     `json:"scope" db:"scope"`
+	State               *string    `json:"-" db:"state"`
+	Nonce               *string    `json:"-" db:"nonce"`
+	CodeChallenge       *string    `json:"-" db:"code_challenge"`
+	CodeChallengeMethod *string    `json:"-" db:"code_challenge_method"`
+	AuthCodeHash        *string    `json:"-" db:"auth_code_hash"`
+	Status              string     `json:"status" db:"status"`
+	// AuthTime is when the user who approved the authorization signed in.
+	AuthTime   *time.Time `json:"auth_time,omitempty" db:"auth_time"`
+	ApprovedAt *time.Time `json:"approved_at,omitempty" db:"approved_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+func (OAuthAuthorization) TableName() string {
+	tableName := "oauth_authorizations"
+	return tableName
+}
+
+func NewOAuthAuthorization(client *OAuthClient, redirectURI, scope, state, nonce, codeChallenge, codeChallengeMethod string) *OAuthAuthorization {
+	authorization := &OAuthAuthorization{
+		ID:          uuid.Must(uuid.NewV4()),
+		ClientID:    client.ID,
+		RedirectURI: redirectURI,
+		Scope:       scope,
+		Status:      OAuthAuthorizationPending.String(),
+	}
+	if state != "" {
+		authorization.State = &state
+	}
+	if nonce != "" {
+		authorization.Nonce = &nonce
+	}
+	if codeChallenge != "" {
+		authorization.CodeChallenge = &codeChallenge
+		authorization.CodeChallengeMethod = &codeChallengeMethod
+	}
+	return authorization
+}
+
+func FindOAuthAuthorizationByID(tx *storage.Connection, id uuid.UUID) (*OAuthAuthorization, error) {
+	return findOAuthAuthorization(tx, "id = ?", id)
+}
+
+func FindOAuthAuthorizationByAuthCode(tx *storage.Connection, authCode string) (*OAuthAuthorization, error) {
+	return findOAuthAuthorization(tx, "auth_code_hash = ? and status = ?", hashAuthCode(authCode), OAuthAuthorizationApproved.String())
+}
+
+func findOAuthAuthorization(tx *storage.Connection, query string, args ...interface{}) (*OAuthAuthorization, error) {
+	obj := &OAuthAuthorization{}
+	if err := tx.Q().Where(query, args...).First(obj); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows {
+			return nil, OAuthAuthorizationNotFoundError{}
+		}
+		return nil, errors.Wrap(err, "error finding oauth authorization")
+	}
+
+	return obj, nil
+}
+
+// Approve records the user's consent and returns the generated
+// authorization code; only its hash is stored, as it is a bearer secret.
+// authTime is when the user signed in, if known.
+func (a *OAuthAuthorization) Approve(tx *storage.Connection, userID uuid.UUID, authTime *time.Time) (string, error) {
+	authCode := crypto.SecureToken(32)
+	authCodeHash := hashAuthCode(authCode)
+	now := time.Now()
+	a.UserID = &userID
+	a.AuthCodeHash = &authCodeHash
+	a.Status = OAuthAuthorizationApproved.String()
+	a.AuthTime = authTime
+	a.ApprovedAt = &now
+	if err := tx.UpdateOnly(a, "user_id", "auth_code_hash", "status", "auth_time", "approved_at"); err != nil {
+		return "", err
+	}
+	return authCode, nil
+}
+
+// authorization codes are long random strings so a plain SHA-256 hash is
+// sufficient
+func hashAuthCode(authCode string) string {
+	sum := sha256.Sum256([]byte(authCode))
+	return hex.EncodeToString(sum[:])
+}
+
+// Redeem removes the authorization once its code has been exchanged. It
+// fails if a concurrent request already redeemed the code, so that an
+// authorization code can only be used once.
+func (a *OAuthAuthorization) Redeem(tx *storage.Connection) error {
+	count, err := tx.RawQuery(
+		"DELETE FROM "+a.TableName()+" WHERE id = ? AND status = ?",
+		a.ID, OAuthAuthorizationApproved.String(),
+	).ExecWithCount()
+	if err != nil {
+		return errors.Wrap(err, "error removing oauth authorization")
+	}
+	if count != 1 {
+		return OAuthAuthorizationNotFoundError{}
+	}
+	return nil
+}
+
+// HasScope reports whether the user granted the scope to the client.
+func (a *OAuthAuthorization) HasScope(scope string) bool {
+	for _, s := range strings.Fields(a.Scope) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *OAuthAuthorization) IsPending() bool {
+	return a.Status == OAuthAuthorizationPending.String()
+}
+
+// IsExpired reports whether the authorization request, or its code once it
+// has been approved, is older than expiryDuration. Codes are valid from the
+// time of approval, however long the user took to consent.
+func (a *OAuthAuthorization) IsExpired(expiryDuration time.Duration) bool {
+	issuedAt := a.CreatedAt
+	if a.ApprovedAt != nil {
+		issuedAt = *a.ApprovedAt
+	}
+	return time.Now().After(issuedAt.Add(expiryDuration))
+}
+
+// VerifyPKCE checks the code verifier if the client sent a code challenge
+// with the authorization request.
+func (a *OAuthAuthorization) VerifyPKCE(codeVerifier string) error {
+	if a.CodeChallenge == nil || a.CodeChallengeMethod == nil {
+		if codeVerifier != "" {
+			return errors.New(InvalidCodeChallengeError)
+		}
+		return nil
+	}
+	return VerifyCodeChallenge(*a.CodeChallenge, *a.CodeChallengeMethod, codeVerifier)
+}
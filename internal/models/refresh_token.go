@@ -44,6 +44,9 @@ type GrantParams struct {
 	SessionNotAfter *time.Time
 	SessionTag      *string
 
+	OAuthClientID *uuid.UUID
+	OAuthScope    *string
+
 	UserAgent string
 	IP        string
 }
@@ -78,29 +81,52 @@ func GrantRefreshTokenSwap(r *http.Request, tx *storage.Connection, user *User,
 	return newToken, err
 }
 
+// FindChildRefreshToken finds the unrevoked token the provided token was
+// swapped for, if any.
+func FindChildRefreshToken(tx *storage.Connection, token *RefreshToken) (*RefreshToken, error) {
+	child := &RefreshToken{}
+	if err := tx.Q().Where("parent = ? and revoked is false", token.Token).Order("id desc").First(child); err != nil {
+		if errors.Cause(err) == sql.ErrNoRows || errors.Is(err, sql.ErrNoRows) {
+			return nil, RefreshTokenNotFoundError{}
+		}
+		return nil, err
+	}
+	return child, nil
+}
+
 // RevokeTokenFamily revokes all refresh tokens that descended from the provided token.
 func RevokeTokenFamily(tx *storage.Connection, token *RefreshToken) error {
-	var err error
 	tablename := (&pop.Model{Value: RefreshToken{}}).TableName()
-	if token.SessionId != nil {
-		err = tx.RawQuery(`update `+tablename+` set revoked = true, updated_at = now() where session_id = ? and revoked = false;`, token.SessionId).Exec()
-	} else {
-		err = tx.RawQuery(`
-		with recursive token_family as (
-			select id, user_id, token, revoked, parent from `+tablename+` where parent = ?
-			union
-			select r.id, r.user_id, r.token, r.revoked, r.parent from `+tablename+` r inner join token_family t on t.token = r.parent
-		)
-		update `+tablename+` r set revoked = true from token_family where token_family.id = r.id;`, token.Token).Exec()
-	}
-	if err != nil {
-		if errors.Cause(err) == sql.ErrNoRows || errors.Is(err, sql.ErrNoRows) {
-			return nil
+
+	// A concurrent swap of a descendant holds its row lock, so the update
+	// waits for it, but doesn't see the token the swap inserted. The update
+	// is repeated until it doesn't find any more tokens, so that the
+	// revocation wins over concurrent swaps.
+	for {
+		var count int
+		var err error
+		if token.SessionId != nil {
+			count, err = tx.RawQuery(`update `+tablename+` set revoked = true, updated_at = now() where session_id = ? and revoked = false;`, token.SessionId).ExecWithCount()
+		} else {
+			count, err = tx.RawQuery(`
+			with recursive token_family as (
+				select id, user_id, token, revoked, parent from `+tablename+` where parent = ?
+				union
+				select r.id, r.user_id, r.token, r.revoked, r.parent from `+tablename+` r inner join token_family t on t.token = r.parent
+			)
+			update `+tablename+` r set revoked = true from token_family where token_family.id = r.id and r.revoked = false;`, token.Token).ExecWithCount()
 		}
+		if err != nil {
+			if errors.Cause(err) == sql.ErrNoRows || errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
 
-		return err
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
 	}
-	return nil
 }
 
 func FindTokenBySessionID(tx *storage.Connection, sessionId *uuid.UUID) (*RefreshToken, error) {
@@ -154,6 +180,11 @@ func createRefreshToken(tx *storage.Connection, user *User, oldToken *RefreshTok
 			session.Tag = params.SessionTag
 		}
 
+		if params.OAuthClientID != nil {
+			session.OAuthClientID = params.OAuthClientID
+			session.OAuthScope = params.OAuthScope
+		}
+
 		if err := tx.Create(session); err != nil {
 			return nil, errors.Wrap(err, "error creating new session")
 		}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"github.com/supabase/auth/internal/storage"
+)
+
+// SchemaMigration is a migration applied by the migrate command.
+type SchemaMigration struct {
+	Version string `db:"version"`
+}
+
+func (SchemaMigration) TableName() string {
+	tableName := "schema_migrations"
+	return tableName
+}
+
+// IsMigrationApplied returns true if the migration with the version was
+// applied to the database.
+func IsMigrationApplied(tx *storage.Connection, version string) (bool, error) {
+	return tx.Q().Where("version = ?", version).Exists(&SchemaMigration{})
+}
@@ -75,6 +75,11 @@ type Session struct {
 	IP          *string    `json:"ip,omitempty" db:"ip"`
 
 	Tag *string `json:"tag" db:"tag"`
+
+	// OAuthClientID is set for sessions of third-party OAuth clients, whose
+	// access tokens are limited to OAuthScope.
+	OAuthClientID *uuid.UUID `json:"oauth_client_id,omitempty" db:"oauth_client_id"`
+	OAuthScope    *string    `json:"oauth_scope,omitempty" db:"oauth_scope"`
 }
 
 func (Session) TableName() string {
@@ -327,6 +332,18 @@ func (s *Session) CalculateAALAndAMR(tx *storage.Connection) (aal string, amr []
 	return aal, amr, nil
 }
 
+// LastMFAVerifiedAt returns when the session was last verified with MFA, or
+// nil if it never was.
+func (s *Session) LastMFAVerifiedAt() *time.Time {
+	for _, claim := range s.AMRClaims {
+		if claim.GetAuthenticationMethod() == TOTPSignIn.String() {
+			verifiedAt := claim.UpdatedAt
+			return &verifiedAt
+		}
+	}
+	return nil
+}
+
 func (s *Session) GetAAL() string {
 	if s.AAL == nil {
 		return ""
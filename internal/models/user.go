@@ -28,6 +28,16 @@ type User struct {
 	EncryptedPassword string     `json:"-" db:"encrypted_password"`
 	EmailConfirmedAt  *time.Time `json:"email_confirmed_at,omitempty" db:"email_confirmed_at"`
 	InvitedAt         *time.Time `json:"invited_at,omitempty" db:"invited_at"`
+	InviteExpiresAt   *time.Time `json:"invite_expires_at,omitempty" db:"invite_expires_at"`
+
+	// InviteRole and InviteAppMetaData are applied once the invite is
+	// accepted
+	InviteRole        *string `json:"-" db:"invite_role"`
+	InviteAppMetaData JSONMap `json:"-" db:"invite_app_metadata"`
+
+	// ApprovalRequestedAt is set on signups held for approval by an admin
+	ApprovalRequestedAt *time.Time `json:"approval_requested_at,omitempty" db:"approval_requested_at"`
+	ApprovedAt          *time.Time `json:"approved_at,omitempty" db:"approved_at"`
 
 	Phone            storage.NullString `json:"phone" db:"phone"`
 	PhoneConfirmedAt *time.Time         `json:"phone_confirmed_at,omitempty" db:"phone_confirmed_at"`
@@ -41,6 +51,10 @@ type User struct {
 	RecoveryToken  string     `json:"-" db:"recovery_token"`
 	RecoverySentAt *time.Time `json:"recovery_sent_at,omitempty" db:"recovery_sent_at"`
 
+	// MagicLinkSentAt is set when the recovery token was sent as a magic link
+	MagicLinkSentAt    *time.Time `json:"-" db:"magic_link_sent_at"`
+	MagicLinkUsedToken string     `json:"-" db:"magic_link_used_token"`
+
 	EmailChangeTokenCurrent  string     `json:"-" db:"email_change_token_current"`
 	EmailChangeTokenNew      string     `json:"-" db:"email_change_token_new"`
 	EmailChange              string     `json:"new_email,omitempty" db:"email_change"`
@@ -163,6 +177,33 @@ func (u *User) SetRole(tx *storage.Connection, roleName string) error {
 	return tx.UpdateOnly(u, "role")
 }
 
+// settleInvite applies the role and app metadata the user was invited with,
+// unless the invite has expired, and clears them either way. It is called
+// when the email is confirmed, which accepts the invite regardless of
+// whether that happens through the invite link, another sign in method or
+// an admin.
+func (u *User) settleInvite(tx *storage.Connection, now time.Time) error {
+	if u.InviteRole == nil && u.InviteAppMetaData == nil && u.InviteExpiresAt == nil {
+		return nil
+	}
+	if u.InviteExpiresAt == nil || now.Before(*u.InviteExpiresAt) {
+		if u.InviteRole != nil {
+			if err := u.SetRole(tx, *u.InviteRole); err != nil {
+				return err
+			}
+		}
+		if u.InviteAppMetaData != nil {
+			if err := u.UpdateAppMetaData(tx, u.InviteAppMetaData); err != nil {
+				return err
+			}
+		}
+	}
+	u.InviteRole = nil
+	u.InviteAppMetaData = nil
+	u.InviteExpiresAt = nil
+	return tx.UpdateOnly(u, "invite_role", "invite_app_metadata", "invite_expires_at")
+}
+
 // HasRole returns true when the users role is set to roleName
 func (u *User) HasRole(roleName string) bool {
 	return u.Role == roleName
@@ -328,7 +369,10 @@ func (u *User) Confirm(tx *storage.Connection) error {
 	u.ConfirmationToken = ""
 	now := time.Now()
 	u.EmailConfirmedAt = &now
-	return tx.UpdateOnly(u, "confirmation_token", "email_confirmed_at")
+	if err := tx.UpdateOnly(u, "confirmation_token", "email_confirmed_at"); err != nil {
+		return err
+	}
+	return u.settleInvite(tx, now)
 }
 
 // ConfirmPhone resets the confimation token and sets the confirm timestamp
@@ -433,8 +477,28 @@ func (u *User) ConfirmPhoneChange(tx *storage.Connection) error {
 
 // Recover resets the recovery token
 func (u *User) Recover(tx *storage.Connection) error {
+	usedToken := u.MagicLinkUsedToken
+	if u.MagicLinkSentAt != nil {
+		usedToken = u.RecoveryToken
+	}
+
+	// only clear the token if it hasn't been used by a concurrent request in
+	// the meantime, so that it can't be used twice
+	count, err := tx.RawQuery(
+		"UPDATE "+u.TableName()+" SET recovery_token = '', magic_link_sent_at = null, magic_link_used_token = ? WHERE id = ? AND recovery_token = ?",
+		usedToken, u.ID, u.RecoveryToken,
+	).ExecWithCount()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return RecoveryTokenUsedError{}
+	}
+
 	u.RecoveryToken = ""
-	return tx.UpdateOnly(u, "recovery_token")
+	u.MagicLinkSentAt = nil
+	u.MagicLinkUsedToken = usedToken
+	return nil
 }
 
 // CountOtherUsers counts how many other users exist besides the one provided
@@ -493,6 +557,12 @@ func FindUserByRecoveryToken(tx *storage.Connection, token string) (*User, error
 	return findUser(tx, "recovery_token = ? and is_sso_user = false", token)
 }
 
+// FindUserByMagicLinkUsedToken finds a user whose last used magic link had
+// the matching token.
+func FindUserByMagicLinkUsedToken(tx *storage.Connection, token string) (*User, error) {
+	return findUser(tx, "magic_link_used_token = ? and is_sso_user = false", token)
+}
+
 // FindUserByEmailChangeToken finds a user with the matching email change token.
 func FindUserByEmailChangeToken(tx *storage.Connection, token string) (*User, error) {
 	return findUser(tx, "is_sso_user = false and (email_change_token_current = ? or email_change_token_new = ?)", token, token)
@@ -585,6 +655,67 @@ func FindUsersInAudience(tx *storage.Connection, aud string, pageParams *Paginat
 	return users, err
 }
 
+// FindPendingInvitesInAudience finds invited users in an audience that have
+// not yet accepted their invite.
+func FindPendingInvitesInAudience(tx *storage.Connection, aud string, pageParams *Pagination) ([]*User, error) {
+	users := []*User{}
+	q := tx.Q().Where("instance_id = ? and aud = ? and invited_at is not null and email_confirmed_at is null", uuid.Nil, aud).Order("invited_at desc")
+
+	var err error
+	if pageParams != nil {
+		err = q.Paginate(int(pageParams.Page), int(pageParams.PerPage)).All(&users)
+		pageParams.Count = uint64(q.Paginator.TotalEntriesSize)
+	} else {
+		err = q.All(&users)
+	}
+
+	return users, err
+}
+
+// IsPendingInvite returns true if the user was invited and has not yet
+// accepted the invite.
+func (u *User) IsPendingInvite() bool {
+	return u.InvitedAt != nil && !u.IsConfirmed()
+}
+
+// FindUsersPendingApprovalInAudience finds users in an audience whose signup
+// has not been approved yet.
+func FindUsersPendingApprovalInAudience(tx *storage.Connection, aud string, pageParams *Pagination) ([]*User, error) {
+	users := []*User{}
+	q := tx.Q().Where("instance_id = ? and aud = ? and approval_requested_at is not null and approved_at is null", uuid.Nil, aud).Order("approval_requested_at asc")
+
+	var err error
+	if pageParams != nil {
+		err = q.Paginate(int(pageParams.Page), int(pageParams.PerPage)).All(&users)
+		pageParams.Count = uint64(q.Paginator.TotalEntriesSize)
+	} else {
+		err = q.All(&users)
+	}
+
+	return users, err
+}
+
+// IsPendingApproval returns true if the signup of the user is held for
+// approval by an admin.
+func (u *User) IsPendingApproval() bool {
+	return u.ApprovalRequestedAt != nil && u.ApprovedAt == nil
+}
+
+// RequestApproval holds the user's signup until it is approved by an admin.
+func (u *User) RequestApproval(tx *storage.Connection) error {
+	now := time.Now()
+	u.ApprovalRequestedAt = &now
+	u.ApprovedAt = nil
+	return tx.UpdateOnly(u, "approval_requested_at", "approved_at")
+}
+
+// Approve approves the user's signup so they can sign in.
+func (u *User) Approve(tx *storage.Connection) error {
+	now := time.Now()
+	u.ApprovedAt = &now
+	return tx.UpdateOnly(u, "approved_at")
+}
+
 // FindUserByEmailChangeCurrentAndAudience finds a user with the matching email change and audience.
 func FindUserByEmailChangeCurrentAndAudience(tx *storage.Connection, email, token, aud string) (*User, error) {
 	return findUser(
@@ -692,6 +823,16 @@ func (u *User) Ban(tx *storage.Connection, duration time.Duration) error {
 	return tx.UpdateOnly(u, "banned_until")
 }
 
+// HasVerifiedFactors checks if the user can verify with MFA
+func (u *User) HasVerifiedFactors() bool {
+	for _, factor := range u.Factors {
+		if factor.IsVerified() {
+			return true
+		}
+	}
+	return false
+}
+
 // IsBanned checks if a user is banned or not
 func (u *User) IsBanned() bool {
 	if u.BannedUntil == nil {
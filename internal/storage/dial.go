@@ -88,6 +88,15 @@ func Dial(config *conf.GlobalConfiguration) (*Connection, error) {
 	return &Connection{db}, nil
 }
 
+// DialReplica connects to the read replica of the database, with the same
+// settings as Dial.
+func DialReplica(config *conf.GlobalConfiguration) (*Connection, error) {
+	replicaConfig := *config
+	replicaConfig.DB.URL = config.DB.ReplicaURL
+
+	return Dial(&replicaConfig)
+}
+
 func registerOpenTelemetryDatabaseStats(db *pop.Connection) {
 	defer func() {
 		if rec := recover(); rec != nil {
@@ -124,6 +133,10 @@ func (e *CommitWithError) Cause() error {
 	return e.Err
 }
 
+func (e *CommitWithError) Unwrap() error {
+	return e.Err
+}
+
 // NewCommitWithError creates an error that can be returned in a pop transaction
 // without rolling back the transaction. This should only be used in cases where
 // you want the transaction to commit but return an error message to the user.
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+	"github.com/supabase/auth/internal/conf"
+	"github.com/supabase/auth/migrations"
+)
+
+// Migrate applies the migrations that haven't been applied yet, see
+// MigrateWithStatus.
+func Migrate(config *conf.GlobalConfiguration) error {
+	return MigrateWithStatus(config, nil)
+}
+
+// MigrateWithStatus applies the migrations that haven't been applied yet.
+// They're the migrations compiled into the binary, or the ones in
+// config.DB.MigrationsPath if it's set. If status isn't nil the status of
+// the migrations is written to it before and after they're applied.
+func MigrateWithStatus(config *conf.GlobalConfiguration, status io.Writer) error {
+	db, err := pop.NewConnection(&pop.ConnectionDetails{
+		Dialect: config.DB.Driver,
+		URL:     config.DB.URL,
+		Options: map[string]string{
+			"migration_table_name": "schema_migrations",
+			"Namespace":            config.DB.Namespace,
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "opening db connection")
+	}
+	defer db.Close()
+
+	if err := db.Open(); err != nil {
+		return errors.Wrap(err, "checking database connection")
+	}
+
+	mig, err := pop.NewMigrationBox(migrationsFS(config), db)
+	if err != nil {
+		return errors.Wrap(err, "creating db migrator")
+	}
+
+	// turn off schema dump
+	mig.SchemaPath = ""
+
+	if status != nil {
+		if err := mig.Status(status); err != nil {
+			return errors.Wrap(err, "migration status")
+		}
+	}
+
+	if err := mig.Up(); err != nil {
+		return errors.Wrap(err, "running db migrations")
+	}
+
+	if status != nil {
+		if err := mig.Status(status); err != nil {
+			return errors.Wrap(err, "migration status")
+		}
+	}
+	return nil
+}
+
+// LatestMigration returns the version of the latest migration Migrate
+// applies.
+func LatestMigration(config *conf.GlobalConfiguration) (string, error) {
+	entries, err := fs.ReadDir(migrationsFS(config), ".")
+	if err != nil {
+		return "", errors.Wrap(err, "reading migrations")
+	}
+
+	// migration versions are timestamps, so the latest sorts last
+	latest := ""
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".up.sql") {
+			continue
+		}
+		if version, _, ok := strings.Cut(name, "_"); ok && version > latest {
+			latest = version
+		}
+	}
+	if latest == "" {
+		return "", errors.New("no migrations found")
+	}
+	return latest, nil
+}
+
+func migrationsFS(config *conf.GlobalConfiguration) fs.FS {
+	if config.DB.MigrationsPath != "" {
+		return os.DirFS(config.DB.MigrationsPath)
+	}
+	return migrations.FS
+}
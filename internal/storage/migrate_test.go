@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/supabase/auth/internal/conf"
+)
+
+func TestLatestMigration(t *testing.T) {
+	entries, err := os.ReadDir("../../migrations")
+	require.NoError(t, err)
+	latest := ""
+	for _, entry := range entries {
+		if version, _, ok := strings.Cut(entry.Name(), "_"); ok && strings.HasSuffix(entry.Name(), ".up.sql") && version > latest {
+			latest = version
+		}
+	}
+
+	// the migrations compiled into the binary are the migrations directory
+	config := &conf.GlobalConfiguration{}
+	version, err := LatestMigration(config)
+	require.NoError(t, err)
+	require.Equal(t, latest, version)
+
+	config.DB.MigrationsPath = "../../migrations"
+	version, err = LatestMigration(config)
+	require.NoError(t, err)
+	require.Equal(t, latest, version)
+
+	config.DB.MigrationsPath = t.TempDir()
+	_, err = LatestMigration(config)
+	require.Error(t, err)
+}
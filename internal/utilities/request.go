@@ -79,6 +79,15 @@ func GetReferrer(r *http.Request, config *conf.GlobalConfiguration) string {
 	return config.SiteURL
 }
 
+// blockedRedirectSchemes can never be redirected to, even if a broad
+// pattern in the allow list matches them
+var blockedRedirectSchemes = map[string]bool{
+	"javascript": true,
+	"data":       true,
+	"vbscript":   true,
+	"file":       true,
+}
+
 func IsRedirectURLValid(config *conf.GlobalConfiguration, redirectURL string) bool {
 	if redirectURL == "" {
 		return false
@@ -87,6 +96,10 @@ func IsRedirectURLValid(config *conf.GlobalConfiguration, redirectURL string) bo
 	base, berr := url.Parse(config.SiteURL)
 	refurl, rerr := url.Parse(redirectURL)
 
+	if rerr != nil || blockedRedirectSchemes[strings.ToLower(refurl.Scheme)] {
+		return false
+	}
+
 	// As long as the referrer came from the site, we will redirect back there
 	if berr == nil && rerr == nil && base.Hostname() == refurl.Hostname() {
 		return true
@@ -129,3 +129,43 @@ func TestGetReferrer(t *tst.T) {
 		})
 	}
 }
+
+func TestIsRedirectURLValidDeepLinks(t *tst.T) {
+	config := conf.GlobalConfiguration{
+		SiteURL:      "https://example.com",
+		URIAllowList: []string{"com.example.app://**", "https://links.example.org/app/**", "**"},
+	}
+	config.ApplyDefaults()
+	cases := []struct {
+		desc        string
+		redirectURL string
+		expected    bool
+	}{
+		{
+			desc:        "custom scheme",
+			redirectURL: "com.example.app://auth/callback",
+			expected:    true,
+		},
+		{
+			desc:        "universal link",
+			redirectURL: "https://links.example.org/app/auth/callback",
+			expected:    true,
+		},
+		{
+			desc:        "javascript scheme is never allowed",
+			redirectURL: "javascript://example.com/%0aalert(1)",
+			expected:    false,
+		},
+		{
+			desc:        "data scheme is never allowed",
+			redirectURL: "data:text/html,hello",
+			expected:    false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *tst.T) {
+			require.Equal(t, c.expected, IsRedirectURLValid(&config, c.redirectURL))
+		})
+	}
+}
@@ -18,7 +18,10 @@ func init() {
 }
 
 func main() {
-	execCtx, execCancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGHUP, syscall.SIGINT)
+	// SIGHUP reloads the configuration of the serve command, which handles
+	// it itself, and is ignored by the other commands instead of killing them
+	signal.Ignore(syscall.SIGHUP)
+	execCtx, execCancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
 	defer execCancel()
 
 	go func() {
@@ -0,0 +1,19 @@
+-- adds the device_codes table used by the OAuth 2.0 device authorization grant (RFC 8628)
+create table if not exists {{ index .Options "Namespace" }}.device_codes(
+       id uuid primary key,
+       client_id uuid not null,
+       user_id uuid null references {{ index .Options "Namespace" }}.users(id) on delete cascade,
+       device_code_hash text not null,
+       user_code text not null,
+       status text not null,
+       polling_interval integer not null,
+       last_polled_at timestamptz null,
+       created_at timestamptz null,
+       updated_at timestamptz null,
+       constraint device_codes_device_code_hash_key unique(device_code_hash),
+       constraint device_codes_user_code_key unique(user_code)
+);
+create index if not exists device_codes_created_at_idx on {{ index .Options "Namespace" }}.device_codes(created_at desc);
+comment on table {{ index .Options "Namespace" }}.device_codes is 'auth: stores pending device authorization requests';
+comment on column {{ index .Options "Namespace" }}.device_codes.device_code_hash is 'auth: SHA-256 hash of the device code, which is a bearer secret';
+comment on column {{ index .Options "Namespace" }}.device_codes.polling_interval is 'auth: seconds the device must wait between polls, increased on every slow_down error';
@@ -0,0 +1,39 @@
+-- adds the tables used when running as an OAuth 2.0 authorization server for third-party clients
+create table if not exists {{ index .Options "Namespace" }}.oauth_clients(
+       id uuid primary key,
+       name text not null,
+       client_type text not null,
+       client_secret_hash text null,
+       redirect_uris jsonb not null,
+       created_at timestamptz null,
+       updated_at timestamptz null
+);
+comment on table {{ index .Options "Namespace" }}.oauth_clients is 'auth: stores third-party clients registered with the OAuth 2.0 authorization server';
+
+create table if not exists {{ index .Options "Namespace" }}.oauth_authorizations(
+       id uuid primary key,
+       client_id uuid not null references {{ index .Options "Namespace" }}.oauth_clients(id) on delete cascade,
+       user_id uuid null references {{ index .Options "Namespace" }}.users(id) on delete cascade,
+       redirect_uri text not null,
+       scope text not null,
+       state text null,
+       nonce text null,
+       code_challenge text null,
+       code_challenge_method text null,
+       auth_code_hash text null,
+       status text not null,
+       auth_time timestamptz null,
+       approved_at timestamptz null,
+       created_at timestamptz null,
+       updated_at timestamptz null,
+       constraint oauth_authorizations_auth_code_hash_key unique(auth_code_hash)
+);
+create index if not exists oauth_authorizations_created_at_idx on {{ index .Options "Namespace" }}.oauth_authorizations(created_at desc);
+comment on table {{ index .Options "Namespace" }}.oauth_authorizations is 'auth: stores authorization requests and codes issued to OAuth clients';
+comment on column {{ index .Options "Namespace" }}.oauth_authorizations.auth_code_hash is 'auth: SHA-256 hash of the authorization code, which is a bearer secret';
+
+-- limits sessions of third-party OAuth clients to the scopes the user granted
+alter table {{ index .Options "Namespace" }}.sessions
+  add column if not exists oauth_client_id uuid null references {{ index .Options "Namespace" }}.oauth_clients(id) on delete cascade,
+  add column if not exists oauth_scope text null;
+comment on column {{ index .Options "Namespace" }}.sessions.oauth_client_id is 'auth: set for sessions of third-party OAuth clients, whose access tokens are limited to oauth_scope';
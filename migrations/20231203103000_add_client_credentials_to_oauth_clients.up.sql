@@ -0,0 +1,6 @@
+-- adds the columns used by the OAuth 2.0 client credentials grant for service accounts
+alter table {{ index .Options "Namespace" }}.oauth_clients
+  add column if not exists client_credentials_enabled boolean not null default false,
+  add column if not exists role text null,
+  add column if not exists claims jsonb null;
+comment on column {{ index .Options "Namespace" }}.oauth_clients.claims is 'auth: additional claims added to access tokens issued with the client credentials grant';
@@ -0,0 +1,6 @@
+-- adds an optional expiry for invites that overrides the default email OTP expiry,
+-- and holds the role and app metadata of an invite until it is accepted
+alter table {{ index .Options "Namespace" }}.users
+  add column if not exists invite_expires_at timestamptz null,
+  add column if not exists invite_role text null,
+  add column if not exists invite_app_metadata jsonb null;
@@ -0,0 +1,8 @@
+-- magic_link_sent_at marks the recovery token as a magic link, which has its
+-- own expiry, and magic_link_used_token is kept to tell apart magic links that
+-- were already used from invalid ones
+alter table {{ index .Options "Namespace" }}.users
+  add column if not exists magic_link_sent_at timestamptz null default null,
+  add column if not exists magic_link_used_token varchar(255) not null default '';
+
+create index if not exists users_magic_link_used_token_idx on {{ index .Options "Namespace" }}.users using btree (magic_link_used_token) where magic_link_used_token <> '';
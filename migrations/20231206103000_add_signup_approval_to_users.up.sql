@@ -0,0 +1,7 @@
+-- approval_requested_at is set on signups that are held for approval by an
+-- admin, approved_at once an admin has approved them
+alter table {{ index .Options "Namespace" }}.users
+  add column if not exists approval_requested_at timestamptz null default null,
+  add column if not exists approved_at timestamptz null default null;
+
+create index if not exists users_approval_requested_at_idx on {{ index .Options "Namespace" }}.users using btree (approval_requested_at) where approval_requested_at is not null and approved_at is null;
@@ -0,0 +1,10 @@
+// Package migrations holds the database migrations, which are compiled into
+// the binary so it doesn't need the migrations directory to migrate.
+package migrations
+
+import "embed"
+
+// FS holds the migration files.
+//
+//go:embed *.sql
+var FS embed.FS